@@ -0,0 +1,153 @@
+package slimy
+
+import (
+	"image"
+	"math"
+	"sort"
+
+	"github.com/vktec/slimy/util"
+)
+
+// A group of nearby results, represented by the best of them
+type Cluster struct {
+	Result     // The best result in the cluster
+	Size   int // The number of results in the cluster
+	// The inclusive bounds of the centres of the results in the cluster
+	MinX, MinZ, MaxX, MaxZ int32
+}
+
+// Decides which results are grouped into the same cluster
+type Linkage struct {
+	// The furthest apart two linked results can be along each axis
+	rx, rz int64
+	linked func(dx, dz int64) bool
+}
+
+// Links results whose centres are at most radius chunks apart
+func WithinRadius(radius float64) Linkage {
+	if !(radius >= 0) {
+		radius = 0
+	}
+	// Results are never this far apart, even diagonally, so larger radii link everything anyway
+	radius = math.Min(radius, 1<<33)
+	r := int64(radius)
+	return Linkage{r, r, func(dx, dz int64) bool {
+		return float64(dx)*float64(dx)+float64(dz)*float64(dz) <= radius*radius
+	}}
+}
+
+// Links results whose masks share at least one chunk.
+// The mask is interpreted the same way as by the searchers, so it should be the mask that was searched with,
+// and weighted should be true if the search was weighted, so that every cell with a weight is included.
+func MasksOverlap(mask image.Image, weighted bool) Linkage {
+	dim := mask.Bounds().Canon()
+	cells := util.MaskOffsets(mask, weighted)
+
+	// Two masks overlap if the offset between them is the difference of two of their cells
+	w, h := dim.Dx(), dim.Dy()
+	offsets := make([]bool, (2*w-1)*(2*h-1))
+	for _, a := range cells {
+		for _, b := range cells {
+			offsets[(a.Y-b.Y+h-1)*(2*w-1)+a.X-b.X+w-1] = true
+		}
+	}
+	return Linkage{int64(w - 1), int64(h - 1), func(dx, dz int64) bool {
+		return offsets[(int(dz)+h-1)*(2*w-1)+int(dx)+w-1]
+	}}
+}
+
+// Groups results that are linked to each other, directly or through other results, and returns the best result from each group.
+// Clusters are sorted best first, according to their best results.
+func ClusterResults(results []Result, link Linkage, order Order) []Cluster {
+	// Bucket the results so only nearby pairs need checking
+	type bucket struct{ x, z int32 }
+	bw, bh := link.rx+1, link.rz+1
+	bucketOf := func(r Result) bucket {
+		return bucket{int32(floorDiv(int64(r.X), bw)), int32(floorDiv(int64(r.Z), bh))}
+	}
+	buckets := make(map[bucket][]int)
+	for i, r := range results {
+		b := bucketOf(r)
+		buckets[b] = append(buckets[b], i)
+	}
+
+	// Union-find over result indices
+	parent := make([]int, len(results))
+	for i := range parent {
+		parent[i] = i
+	}
+	find := func(i int) int {
+		for parent[i] != i {
+			parent[i] = parent[parent[i]]
+			i = parent[i]
+		}
+		return i
+	}
+
+	for i, a := range results {
+		b := bucketOf(a)
+		for bz := b.z - 1; bz <= b.z+1; bz++ {
+			for bx := b.x - 1; bx <= b.x+1; bx++ {
+				for _, j := range buckets[bucket{bx, bz}] {
+					if j <= i {
+						continue
+					}
+					dx, dz := int64(results[j].X)-int64(a.X), int64(results[j].Z)-int64(a.Z)
+					if abs64(dx) <= link.rx && abs64(dz) <= link.rz && link.linked(dx, dz) {
+						parent[find(j)] = find(i)
+					}
+				}
+			}
+		}
+	}
+
+	index := make(map[int]int) // Root result to cluster
+	var clusters []Cluster
+	for i, r := range results {
+		root := find(i)
+		ci, ok := index[root]
+		if !ok {
+			index[root] = len(clusters)
+			clusters = append(clusters, Cluster{r, 1, r.X, r.Z, r.X, r.Z})
+			continue
+		}
+
+		c := &clusters[ci]
+		if r.OrderBefore(c.Result, order) {
+			c.Result = r
+		}
+		c.Size++
+		if r.X < c.MinX {
+			c.MinX = r.X
+		}
+		if r.Z < c.MinZ {
+			c.MinZ = r.Z
+		}
+		if r.X > c.MaxX {
+			c.MaxX = r.X
+		}
+		if r.Z > c.MaxZ {
+			c.MaxZ = r.Z
+		}
+	}
+
+	sort.Slice(clusters, func(i, j int) bool {
+		return clusters[i].OrderBefore(clusters[j].Result, order)
+	})
+	return clusters
+}
+
+func floorDiv(a, b int64) int64 {
+	q := a / b
+	if a%b != 0 && a < 0 {
+		q--
+	}
+	return q
+}
+
+func abs64(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,159 @@
+package slimy
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/vktec/slimy/util"
+)
+
+func TestClusterResults(t *testing.T) {
+	results := []Result{
+		{0, 0, 30, 30}, {1, 0, 32, 32}, {2, 1, 31, 31}, // One cluster, chained together
+		{10, 10, 35, 35},                 // On its own
+		{-5, 3, 29, 29}, {-5, 5, 29, 29}, // Exactly 2 apart
+	}
+	clusters := ClusterResults(results, WithinRadius(2), Descending)
+	expected := []Cluster{
+		{Result{10, 10, 35, 35}, 1, 10, 10, 10, 10},
+		{Result{1, 0, 32, 32}, 3, 0, 0, 2, 1},
+		{Result{-5, 3, 29, 29}, 2, -5, 3, -5, 5},
+	}
+	if len(clusters) != len(expected) {
+		t.Fatalf("Expected %d clusters, got %d: %v", len(expected), len(clusters), clusters)
+	}
+	for i := range clusters {
+		if clusters[i] != expected[i] {
+			t.Errorf("Cluster %d: expected %v, got %v", i, expected[i], clusters[i])
+		}
+	}
+}
+
+// Clusters by checking every pair of results
+func bruteForceClusters(results []Result, linked func(a, b Result) bool) (sizes map[Result]int) {
+	cluster := make([]int, len(results))
+	for i := range cluster {
+		cluster[i] = i
+	}
+	for changed := true; changed; {
+		changed = false
+		for i := range results {
+			for j := range results {
+				if cluster[j] < cluster[i] && linked(results[i], results[j]) {
+					cluster[i] = cluster[j]
+					changed = true
+				}
+			}
+		}
+	}
+
+	best := make(map[int]Result)
+	count := make(map[int]int)
+	for i, r := range results {
+		b, ok := best[cluster[i]]
+		if !ok || r.OrderBefore(b, Descending) {
+			best[cluster[i]] = r
+		}
+		count[cluster[i]]++
+	}
+	sizes = make(map[Result]int)
+	for c, r := range best {
+		sizes[r] = count[c]
+	}
+	return sizes
+}
+
+func checkClusters(t *testing.T, clusters []Cluster, expected map[Result]int) {
+	if len(clusters) != len(expected) {
+		t.Fatalf("Expected %d clusters, got %d", len(expected), len(clusters))
+	}
+	for i, c := range clusters {
+		if expected[c.Result] != c.Size {
+			t.Fatalf("Cluster %v: expected size %d, got %d", c.Result, expected[c.Result], c.Size)
+		}
+		if i > 0 && c.OrderBefore(clusters[i-1].Result, Descending) {
+			t.Fatalf("Clusters out of order at index %d", i)
+		}
+	}
+}
+
+func randomResults(rng *rand.Rand, n int) []Result {
+	results := make([]Result, n)
+	seen := make(map[[2]int32]bool)
+	for i := range results {
+		x, z := rng.Int31n(200)-100, rng.Int31n(200)-100
+		for seen[[2]int32{x, z}] {
+			x, z = rng.Int31n(200)-100, rng.Int31n(200)-100
+		}
+		seen[[2]int32{x, z}] = true
+		count := uint(rng.Intn(10))
+		results[i] = Result{x, z, count, float64(count)}
+	}
+	return results
+}
+
+func TestClusterRadius(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for _, radius := range []float64{0, 1, 2.5, 7} {
+		results := randomResults(rng, 500)
+		expected := bruteForceClusters(results, func(a, b Result) bool {
+			dx, dz := float64(a.X-b.X), float64(a.Z-b.Z)
+			return dx*dx+dz*dz <= radius*radius
+		})
+		checkClusters(t, ClusterResults(results, WithinRadius(radius), Descending), expected)
+	}
+}
+
+func TestClusterMasks(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	mask := util.GenDonut(2, 4)
+	overlap := func(a, b Result) bool {
+		for y := -4; y <= 4; y++ {
+			for x := -4; x <= 4; x++ {
+				bx, by := x+int(a.X-b.X), y+int(a.Z-b.Z)
+				if util.InMask(mask.At(x, y)) && util.InMask(mask.At(bx, by)) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	results := randomResults(rng, 400)
+	checkClusters(t, ClusterResults(results, MasksOverlap(mask, false), Descending), bruteForceClusters(results, overlap))
+}
+
+func TestClusterWeightedMasks(t *testing.T) {
+	// A dim cell on the left, which only has a weight, and a bright cell on the right
+	mask := image.NewGray(image.Rect(0, 0, 5, 1))
+	mask.SetGray(0, 0, color.Gray{0x40})
+	mask.SetGray(4, 0, color.Gray{0xff})
+	results := []Result{{X: 0, Z: 0}, {X: 4, Z: 0}}
+
+	if clusters := ClusterResults(results, MasksOverlap(mask, false), Descending); len(clusters) != 2 {
+		t.Errorf("Expected 2 clusters without weights, got %d", len(clusters))
+	}
+	if clusters := ClusterResults(results, MasksOverlap(mask, true), Descending); len(clusters) != 1 {
+		t.Errorf("Expected 1 cluster with weights, got %d", len(clusters))
+	}
+}
+
+func TestClusterLargeRadius(t *testing.T) {
+	cases := []struct {
+		radius   float64
+		results  []Result
+		clusters int
+	}{
+		{40000, []Result{{X: 0, Z: 0}, {X: 39000, Z: 5000}}, 1},
+		{40000, []Result{{X: 0, Z: 0}, {X: 40001, Z: 0}}, 2},
+		{1e300, []Result{{X: math.MinInt32, Z: math.MinInt32}, {X: math.MaxInt32, Z: math.MaxInt32}}, 1},
+		{math.NaN(), []Result{{X: 0, Z: 0}, {X: 0, Z: 0}, {X: 1, Z: 0}}, 2},
+	}
+	for _, c := range cases {
+		if clusters := ClusterResults(c.results, WithinRadius(c.radius), Descending); len(clusters) != c.clusters {
+			t.Errorf("Radius %v: expected %d clusters, got %d", c.radius, c.clusters, len(clusters))
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/vktec/slimy"
+	"github.com/vktec/slimy/cpu"
+)
+
+func formatBlocksCSV(results []slimy.BlockResult) error {
+	if _, err := fmt.Println("Player Block X,Player Block Z,Score"); err != nil {
+		return err
+	}
+	for _, result := range results {
+		if _, err := fmt.Print(result.X, ",", result.Z, ",", result.Score, "\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func formatBlocksJSON(results []slimy.BlockResult) error {
+	return json.NewEncoder(os.Stdout).Encode(results)
+}
+
+func formatBlocksNDJSON(results []slimy.BlockResult) error {
+	enc := json.NewEncoder(os.Stdout)
+	for _, result := range results {
+		if err := enc.Encode(result); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func formatBlocksHuman(results []slimy.BlockResult) error {
+	switch len(results) {
+	case 0:
+		_, err := fmt.Println("No results")
+		return err
+	case 1:
+		if _, err := fmt.Println("1 result:"); err != nil {
+			return err
+		}
+	default:
+		if _, err := fmt.Println(len(results), "results:"); err != nil {
+			return err
+		}
+	}
+	for _, result := range results {
+		if _, err := fmt.Printf("(%8d, %8d) %7.2f chunks\n", result.X, result.Z, result.Score); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var blockFmter func([]slimy.BlockResult) error
+
+// Searches for AFK positions in the chunks from x0, z0 to x1, z1
+func runAFKSearch(world cpu.World, workerCount int, x0, z0, x1, z1 int32, threshold slimy.Threshold) {
+	// Stop the search on interrupt, so that partial results can be printed
+	ctx, cancel := interruptContext()
+	defer cancel()
+
+	opts := slimy.Options{Progress: progressPrinter("chunks"), Limit: resultLimit}
+	fmt.Fprintf(os.Stderr, "Searching blocks in chunks (%d, %d) to (%d, %d)\n", x0, z0, x1, z1)
+	start := time.Now()
+	results, err := world.SearchAFK(ctx, workerCount, x0, z0, x1, z1, threshold, opts)
+	end := time.Now()
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Search interrupted after %s, showing partial results\n", end.Sub(start))
+	} else {
+		fmt.Fprintf(os.Stderr, "Search finished in %s\n", end.Sub(start))
+	}
+	blockFmter(results)
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/vktec/slimy"
+	"github.com/vktec/slimy/cpu"
+)
+
+func runCheckpointSearch(s *cpu.Searcher, x0, z0, x1, z1 int32, threshold slimy.Threshold, worldSeed int64, name string, resume bool) {
+	cp := s.NewCheckpoint(x0, z0, x1, z1, threshold, worldSeed)
+	if resume {
+		saved, err := cpu.LoadCheckpoint(name)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Could not load checkpoint:", err)
+			os.Exit(2)
+		}
+		if err := saved.Check(cp); err != nil {
+			fmt.Fprintln(os.Stderr, "Cannot resume:", err)
+			os.Exit(2)
+		}
+		cp = saved
+		fmt.Fprintf(os.Stderr, "Resuming with %d of %d tiles done and %d results found\n", cp.TilesDone(), cp.Tiles, len(cp.Results))
+	} else if _, err := os.Stat(name); err == nil {
+		fmt.Fprintf(os.Stderr, "Checkpoint %s already exists, use -resume to continue it\n", name)
+		os.Exit(2)
+	}
+
+	// Stop the search on interrupt, so that partial results can be printed
+	ctx, cancel := interruptContext()
+	defer cancel()
+
+	opts := slimy.Options{Progress: progressPrinter("chunks"), Limit: resultLimit}
+	if clusterLink != nil {
+		// The limit applies to clusters, which can only be found from every result
+		opts.Limit = 0
+	}
+	save := func(cp *cpu.Checkpoint) error { return cp.Save(name) }
+
+	fmt.Fprintf(os.Stderr, "Searching (%d, %d) to (%d, %d), saving progress to %s\n", x0, z0, x1, z1, name)
+	start := time.Now()
+	results, err := s.SearchCheckpoint(ctx, cp, save, opts)
+	end := time.Now()
+	fmt.Fprintln(os.Stderr)
+	if ctx.Err() != nil {
+		fmt.Fprintf(os.Stderr, "Search interrupted after %s, showing partial results. Use -resume to continue it\n", end.Sub(start))
+	} else if err != nil {
+		fmt.Fprintln(os.Stderr, "Search stopped:", err)
+		os.Exit(2)
+	} else {
+		fmt.Fprintf(os.Stderr, "Search finished in %s\n", end.Sub(start))
+	}
+	outputResults(results, threshold)
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"os"
+	"strconv"
+
+	"github.com/vktec/slimy"
+)
+
+// Parses the argument to -cluster, which is either "mask" or a radius in chunks
+func parseLinkage(s string, mask image.Image) (slimy.Linkage, error) {
+	if s == "mask" {
+		return slimy.MasksOverlap(mask, weightedMask), nil
+	}
+	radius, err := strconv.ParseFloat(s, 64)
+	if err != nil || radius < 0 {
+		return slimy.Linkage{}, fmt.Errorf("Cluster must be 'mask' or a radius, not %q", s)
+	}
+	return slimy.WithinRadius(radius), nil
+}
+
+func formatClustersCSV(clusters []slimy.Cluster) error {
+	header := "Center Chunk X,Center Chunk Z,Slime Chunk Count"
+	if weightedMask {
+		header += ",Score"
+	}
+	if _, err := fmt.Println(header + ",Cluster Size,Cluster Min X,Cluster Min Z,Cluster Max X,Cluster Max Z"); err != nil {
+		return err
+	}
+	for _, c := range clusters {
+		if _, err := fmt.Print(c.X, ",", c.Z, ",", c.Count); err != nil {
+			return err
+		}
+		if weightedMask {
+			if _, err := fmt.Print(",", formatScore(c.Score)); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Print(",", c.Size, ",", c.MinX, ",", c.MinZ, ",", c.MaxX, ",", c.MaxZ, "\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func formatClustersJSON(clusters []slimy.Cluster) error {
+	return json.NewEncoder(os.Stdout).Encode(clusters)
+}
+
+func formatClustersNDJSON(clusters []slimy.Cluster) error {
+	enc := json.NewEncoder(os.Stdout)
+	for _, c := range clusters {
+		if err := enc.Encode(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func formatClustersHuman(clusters []slimy.Cluster) error {
+	switch len(clusters) {
+	case 0:
+		_, err := fmt.Println("No results")
+		return err
+	case 1:
+		if _, err := fmt.Println("1 cluster:"); err != nil {
+			return err
+		}
+	default:
+		if _, err := fmt.Println(len(clusters), "clusters:"); err != nil {
+			return err
+		}
+	}
+	for _, c := range clusters {
+		var err error
+		if weightedMask {
+			_, err = fmt.Printf("(%6d, %6d) %7.2f score (%d chunks)", c.X, c.Z, c.Score, c.Count)
+		} else {
+			_, err = fmt.Printf("(%6d, %6d) %3d chunks", c.X, c.Z, c.Count)
+		}
+		if err != nil {
+			return err
+		}
+		if c.Size == 1 {
+			_, err = fmt.Println(", on its own")
+		} else {
+			_, err = fmt.Printf(", best of %d from (%d, %d) to (%d, %d)\n", c.Size, c.MinX, c.MinZ, c.MaxX, c.MaxZ)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var clusterFmter func([]slimy.Cluster) error
+var clusterLink *slimy.Linkage // Set if results should be clustered before output
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vktec/slimy/cpu"
+)
+
+// Reads observed chunks from r, one per line, as X,Z for a slime chunk or X,Z,S where S is true or false.
+// Blank lines and lines starting with # are ignored.
+func readObservations(r io.Reader) (obs []cpu.Observation, err error) {
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		parts := strings.Split(text, ",")
+		if len(parts) != 2 && len(parts) != 3 {
+			return nil, fmt.Errorf("line %d: observation must be of the form 'X,Z' or 'X,Z,slime'", line)
+		}
+		var coords [2]int64
+		for i := range coords {
+			coords[i], err = strconv.ParseInt(strings.TrimSpace(parts[i]), 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+		}
+		slime := true
+		if len(parts) == 3 {
+			slime, err = strconv.ParseBool(strings.TrimSpace(parts[2]))
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+		}
+		obs = append(obs, cpu.Observation{X: int32(coords[0]), Z: int32(coords[1]), Slime: slime})
+	}
+	return obs, scanner.Err()
+}
+
+func crackMain(args []string) {
+	flags := flag.NewFlagSet("crack", flag.ExitOnError)
+	workerCount := flags.Int("j", runtime.GOMAXPROCS(0), "number of concurrent workers")
+	flags.Usage = func() {
+		cmd := filepath.Base(os.Args[0])
+		fmt.Fprintf(os.Stderr, "Usage: %s crack [options] [file]\n\n", cmd)
+		fmt.Fprintln(os.Stderr, "Finds the lower 48 bits of world seeds that match a list of observed chunks.")
+		fmt.Fprintln(os.Stderr, "Each line of the file (or stdin, if no file is given) is X,Z for a slime chunk, or X,Z,false for a chunk that is not a slime chunk.")
+		fmt.Fprintln(os.Stderr)
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	var obs []cpu.Observation
+	var err error
+	switch flags.NArg() {
+	case 0:
+		obs, err = readObservations(os.Stdin)
+	case 1:
+		var f *os.File
+		f, err = os.Open(flags.Arg(0))
+		if err != nil {
+			break
+		}
+		obs, err = readObservations(f)
+		f.Close()
+	default:
+		flags.Usage()
+		os.Exit(1)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not read observations:", err)
+		os.Exit(2)
+	}
+
+	ctx, cancel := interruptContext()
+	defer cancel()
+
+	fmt.Fprintf(os.Stderr, "Cracking from %d observed chunks\n", len(obs))
+	slime := 0
+	for _, o := range obs {
+		if o.Slime {
+			slime++
+		}
+	}
+	if slime > 0 && slime < fewSlimeChunks {
+		fmt.Fprintf(os.Stderr, "Cracking from only %d slime chunks may take a long time. Each additional slime chunk roughly halves it\n", slime)
+	}
+	start := time.Now()
+	seeds, err := cpu.Crack(ctx, *workerCount, obs, progressPrinter("seeds"))
+	end := time.Now()
+	fmt.Fprintln(os.Stderr)
+	if ctx.Err() != nil {
+		fmt.Fprintf(os.Stderr, "Cracking interrupted after %s, showing partial candidates\n", end.Sub(start))
+	} else if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	} else {
+		fmt.Fprintf(os.Stderr, "Cracking finished in %s\n", end.Sub(start))
+	}
+
+	for _, seed := range seeds {
+		fmt.Println(seed)
+	}
+
+	switch len(seeds) {
+	case 0:
+		fmt.Fprintln(os.Stderr, "No seeds match the observations")
+	case 1:
+		fmt.Fprintln(os.Stderr, "Found 1 candidate. Any seed with the same lower 48 bits has the same slime chunks")
+	default:
+		slime, random := cpu.ObservationsNeeded(len(seeds))
+		fmt.Fprintf(os.Stderr, "Found %d candidates. About %d more slime chunks, or %d more chunks chosen at random, should narrow them down to one\n", len(seeds), slime, random)
+		// Nearby chunks are often slime chunks in many of the candidates, so suggest one that tells them apart
+		if ctx.Err() == nil {
+			x0, z0, x1, z1 := observedArea(obs)
+			fmt.Fprintln(os.Stderr, "Looking for a chunk that tells the candidates apart")
+			x, z, n, err := cpu.SplitCandidates(ctx, seeds, x0-splitMargin, z0-splitMargin, x1+splitMargin, z1+splitMargin, progressPrinter("chunks"))
+			fmt.Fprintln(os.Stderr)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "Interrupted, so the best chunk found so far may not be the best overall")
+			}
+			fmt.Fprintf(os.Stderr, "Chunk (%d, %d) is a slime chunk in %d of the candidates, so observing it next would rule out at least %d\n", x, z, n, min(n, len(seeds)-n))
+		}
+	}
+}
+
+const (
+	// How far from the observed chunks to look for a chunk that splits the candidates
+	splitMargin = 16
+	// Cracking from fewer slime chunks than this is slow, since less of the seed can be found before trying every value of the rest
+	fewSlimeChunks = 18
+)
+
+// Returns the area covered by the observations, with the end exclusive
+func observedArea(obs []cpu.Observation) (x0, z0, x1, z1 int32) {
+	x0, z0 = obs[0].X, obs[0].Z
+	x1, z1 = x0+1, z0+1
+	for _, o := range obs[1:] {
+		if o.X < x0 {
+			x0 = o.X
+		}
+		if o.Z < z0 {
+			z0 = o.Z
+		}
+		if o.X >= x1 {
+			x1 = o.X + 1
+		}
+		if o.Z >= z1 {
+			z1 = o.Z + 1
+		}
+	}
+	return x0, z0, x1, z1
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
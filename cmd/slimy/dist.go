@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"image"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"github.com/vktec/slimy"
+	"github.com/vktec/slimy/cpu"
+	"github.com/vktec/slimy/dist"
+	"github.com/vktec/slimy/gpu"
+	"github.com/vktec/slimy/util"
+)
+
+func coordinateMain(args []string) {
+	flags := flag.NewFlagSet("coordinate", flag.ExitOnError)
+	listen := flags.String("listen", "127.0.0.1:8080", "`address` to listen for workers on (use :8080 to accept workers from other machines)")
+	tileSize := flags.Int("tile", 2048, "width of each tile handed to a worker, in `chunks`")
+	timeout := flags.Duration("timeout", 10*time.Minute, "give a tile to another worker if it isn't finished within this `duration`")
+	outputFormat := flags.String("f", "human", "output `format` (valid options: csv, json, ndjson, human)")
+	flags.IntVar(&resultLimit, "n", 0, "maximum `number` of results to output, keeping the best (0 for no limit)")
+	editionName := flags.String("edition", "java", "Minecraft `edition` (options: java, bedrock)")
+	mask := flags.String("mask", "", "mask image `file`name, or a mask spec such as annulus(1,8) (see the mask command)")
+	flags.BoolVar(&weightedMask, "weighted", false, "weight each chunk of the mask by the brightness of its pixel, so results are scored rather than counted")
+	pos := flags.String("pos", "0,0", "search center `position`")
+	verbose := flags.Bool("v", false, "log each tile as it is issued and completed")
+	flags.Usage = func() {
+		cmd := filepath.Base(os.Args[0])
+		fmt.Fprintf(os.Stderr, "Usage: %s coordinate [options] seed range threshold\n\n", cmd)
+		fmt.Fprintln(os.Stderr, "Splits a search into tiles and serves them over HTTP to workers, started with the worker command, then prints the merged results.")
+		fmt.Fprintln(os.Stderr, "Tiles whose worker disappears are given to another worker after the timeout.")
+		fmt.Fprintln(os.Stderr, "By default only workers on this machine can connect. To spread the search over several machines, pass -listen :8080 and start workers with this machine's address.")
+		fmt.Fprintln(os.Stderr)
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 3 {
+		flags.Usage()
+		os.Exit(1)
+	}
+	setFormat(*outputFormat)
+	if *tileSize <= 0 {
+		fmt.Fprintln(os.Stderr, "Tile size must be positive")
+		os.Exit(2)
+	}
+
+	var maskImg image.Image
+	if *mask == "" {
+		maskImg = util.GenDonut(1, 8)
+	} else {
+		var err error
+		maskImg, err = loadMask(*mask)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	maskData, err := dist.EncodeMask(maskImg)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	edition := parseEditionArg(*editionName)
+	centerPos := parsePosArg(*pos)
+	seed := parseSeedArg(flags.Arg(0))
+	x0, z0, x1, z1 := searchArea(centerPos, parseRangeArg(flags.Arg(1)))
+	threshold := parseThresholdArg(flags.Arg(2))
+
+	job := dist.Job{Seed: seed, Edition: edition, Threshold: threshold, Mask: maskData, Weighted: weightedMask, Limit: resultLimit}
+	coord := dist.NewCoordinator(job, x0, z0, x1, z1, int32(*tileSize), *timeout, progressPrinter("chunks"))
+	if *verbose {
+		coord.Log = func(format string, args ...interface{}) {
+			fmt.Fprintf(os.Stderr, "\r\x1b[K"+format+"\n", args...)
+		}
+	}
+
+	l, err := net.Listen("tcp", *listen)
+	if err != nil {
+		log.Fatal(err)
+	}
+	server := &http.Server{Handler: coord}
+	go server.Serve(l)
+
+	// Stop waiting on interrupt, so that partial results can be printed
+	ctx, cancel := interruptContext()
+	defer cancel()
+
+	fmt.Fprintf(os.Stderr, "Coordinating a search of (%d, %d) to (%d, %d) on %s\n", x0, z0, x1, z1, l.Addr())
+	start := time.Now()
+	results, err := coord.Wait(ctx)
+	end := time.Now()
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Search interrupted after %s, showing partial results\n", end.Sub(start))
+	} else {
+		fmt.Fprintf(os.Stderr, "Search finished in %s\n", end.Sub(start))
+	}
+
+	outputResults(results, threshold)
+
+	if err == nil {
+		// Keep serving for long enough that waiting workers hear the job is finished
+		select {
+		case <-time.After(2 * dist.DefaultPollInterval):
+		case <-ctx.Done():
+		}
+	}
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), time.Second)
+	defer shutdownCancel()
+	server.Shutdown(shutdownCtx)
+}
+
+func workerMain(args []string) {
+	flags := flag.NewFlagSet("worker", flag.ExitOnError)
+	workerCount := flags.Int("j", runtime.GOMAXPROCS(0), "number of concurrent workers (cpu only)")
+	method := flags.String("m", "gpu", "search method to use (options: cpu, gpu)")
+	hostname, _ := os.Hostname()
+	name := flags.String("name", fmt.Sprint(hostname, "-", os.Getpid()), "`name` to identify this worker to the coordinator")
+	flags.Usage = func() {
+		cmd := filepath.Base(os.Args[0])
+		fmt.Fprintf(os.Stderr, "Usage: %s worker [options] url\n\n", cmd)
+		fmt.Fprintln(os.Stderr, "Searches tiles for a coordinator, started with the coordinate command, until its search is finished.")
+		fmt.Fprintln(os.Stderr, "If the coordinator can't be reached or fails, the worker keeps retrying until it is interrupted.")
+		fmt.Fprintln(os.Stderr)
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(1)
+	}
+
+	ctx, cancel := interruptContext()
+	defer cancel()
+
+	w := &dist.Worker{Name: *name, URL: flags.Arg(0)}
+	job, err := w.Job(ctx)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not fetch job:", err)
+		os.Exit(2)
+	}
+	maskImg, err := job.MaskImage()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not decode mask:", err)
+		os.Exit(2)
+	}
+
+	var searcher slimy.Searcher
+	switch *method {
+	case "gpu":
+		searcher, err = gpu.NewSearcher(maskImg, job.Edition, job.Weighted)
+	case "cpu":
+		searcher, err = cpu.NewSearcher(*workerCount, maskImg, job.Edition, job.Weighted)
+	default:
+		fmt.Fprintln(os.Stderr, "Method must be one of: cpu, gpu")
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	defer searcher.Destroy()
+
+	w.Progress = func(tile dist.Tile, p slimy.Progress) {
+		progressPrinter(fmt.Sprint("chunks of tile ", tile.Index))(p)
+	}
+	w.Log = func(format string, args ...interface{}) {
+		fmt.Fprintf(os.Stderr, "\r\x1b[K"+format+"\n", args...)
+	}
+
+	fmt.Fprintf(os.Stderr, "Searching seed %d for %s\n", job.Seed, w.URL)
+	err = w.Run(ctx, job, searcher)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Worker stopped:", err)
+		os.Exit(2)
+	}
+	fmt.Fprintln(os.Stderr, "Job finished")
+}
@@ -24,7 +24,8 @@ type App struct {
 	gll.GL420
 
 	worldSeed int64
-	threshold int
+	edition   slimy.Edition
+	threshold slimy.Threshold
 
 	win *glfw.Window
 	vao uint32
@@ -37,9 +38,9 @@ type App struct {
 	maskTex   uint32
 	maskDim   image.Point
 
-	uSlimeView, uSlimeDim, uSlimeWorldSeed, uSlimeWorldSeedV int32
-	uMaskView, uMaskDim, uMaskOrigin                         int32
-	uGridView, uGridDim                                      int32
+	uSlimeView, uSlimeDim, uSlimeWorldSeed, uSlimeWorldSeedV, uSlimeBedrock int32
+	uMaskView, uMaskDim, uMaskOrigin                                        int32
+	uGridView, uGridDim                                                     int32
 
 	results []slimy.Result
 	damaged bool
@@ -50,9 +51,10 @@ type App struct {
 	panX, panZ, zoom float32
 }
 
-func NewApp(worldSeed int64, threshold int, centerPos [2]int, maskImg image.Image, vsync bool) (app *App, err error) {
+func NewApp(worldSeed int64, edition slimy.Edition, threshold slimy.Threshold, centerPos [2]int, maskImg image.Image, vsync bool) (app *App, err error) {
 	app = &App{
 		worldSeed: worldSeed,
+		edition:   edition,
 		threshold: threshold,
 
 		panX: float32(centerPos[0]),
@@ -98,6 +100,7 @@ func NewApp(worldSeed int64, threshold int, centerPos [2]int, maskImg image.Imag
 	app.uSlimeDim = app.GetUniformLocation(app.slimeProg, gll.Str("dim\000"))
 	app.uSlimeWorldSeed = app.GetUniformLocation(app.slimeProg, gll.Str("worldSeed\000"))
 	app.uSlimeWorldSeedV = app.GetUniformLocation(app.slimeProg, gll.Str("worldSeedV\000"))
+	app.uSlimeBedrock = app.GetUniformLocation(app.slimeProg, gll.Str("bedrock\000"))
 
 	app.maskProg, err = gpu.BuildShader(app, fsVert, maskFrag)
 	if err != nil {
@@ -115,7 +118,7 @@ func NewApp(worldSeed int64, threshold int, centerPos [2]int, maskImg image.Imag
 	app.uGridDim = app.GetUniformLocation(app.gridProg, gll.Str("dim\000"))
 
 	app.maskDim = maskImg.Bounds().Canon().Size()
-	app.maskTex = gpu.UploadMask(app, maskImg)
+	app.maskTex = gpu.UploadMask(app, maskImg, weightedMask)
 
 	app.win.SetCursorPosCallback(app.CursorPos)
 	app.win.SetMouseButtonCallback(app.MouseButton)
@@ -123,7 +126,7 @@ func NewApp(worldSeed int64, threshold int, centerPos [2]int, maskImg image.Imag
 	app.win.SetRefreshCallback(app.Refresh)
 	app.win.SetSizeCallback(app.Resize)
 
-	app.s, err = gpu.NewGLFWSearcher(maskImg)
+	app.s, err = gpu.NewGLFWSearcher(maskImg, edition, weightedMask)
 	if err != nil {
 		return nil, err
 	}
@@ -168,6 +171,7 @@ func (app *App) Draw() {
 		app.Uniform1i64ARB(app.uSlimeWorldSeed, app.worldSeed)
 	}
 	app.Uniform2ui(app.uSlimeWorldSeedV, uint32(app.worldSeed>>32), uint32(app.worldSeed))
+	gpu.SetEdition(app, app.uSlimeBedrock, app.edition)
 	app.DrawArrays(gll.TRIANGLES, 0, 3)
 
 	app.UseProgram(app.gridProg)
@@ -1,13 +1,16 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
 	"image"
 	"log"
+	"math"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"runtime"
 	"strconv"
@@ -21,11 +24,11 @@ import (
 )
 
 func formatCSV(results []slimy.Result) error {
-	if _, err := fmt.Println("Center Chunk X,Center Chunk Z,Slime Chunk Count"); err != nil {
+	if err := csvStreamer.header(); err != nil {
 		return err
 	}
 	for _, result := range results {
-		if _, err := fmt.Print(result.X, ",", result.Z, ",", result.Count, "\n"); err != nil {
+		if err := csvStreamer.result(result); err != nil {
 			return err
 		}
 	}
@@ -33,9 +36,78 @@ func formatCSV(results []slimy.Result) error {
 }
 
 func formatJSON(results []slimy.Result) error {
+	if resultStats != nil {
+		rated := make([]ratedResult, len(results))
+		for i, result := range results {
+			rated[i] = rateResult(result)
+		}
+		return json.NewEncoder(os.Stdout).Encode(rated)
+	}
 	return json.NewEncoder(os.Stdout).Encode(results)
 }
 
+func formatNDJSON(results []slimy.Result) error {
+	for _, result := range results {
+		if err := ndjsonStreamer.result(result); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Writes results one at a time, as they are found
+type streamer struct {
+	header func() error
+	result func(slimy.Result) error
+}
+
+var csvStreamer = streamer{
+	func() error {
+		header := "Center Chunk X,Center Chunk Z,Slime Chunk Count"
+		if weightedMask {
+			header += ",Score"
+		}
+		if resultStats != nil {
+			header += ",P-Value,Rarity"
+		}
+		_, err := fmt.Println(header)
+		return err
+	},
+	func(result slimy.Result) error {
+		if _, err := fmt.Print(result.X, ",", result.Z, ",", result.Count); err != nil {
+			return err
+		}
+		if weightedMask {
+			if _, err := fmt.Print(",", formatScore(result.Score)); err != nil {
+				return err
+			}
+		}
+		if resultStats != nil {
+			rated := rateResult(result)
+			if _, err := fmt.Print(",", formatScore(rated.PValue), ",", formatScore(rated.Rarity)); err != nil {
+				return err
+			}
+		}
+		_, err := fmt.Println()
+		return err
+	},
+}
+
+// Formats a weighted score with as few digits as possible
+func formatScore(score float64) string {
+	return strconv.FormatFloat(score, 'f', -1, 64)
+}
+
+var ndjsonStreamer = streamer{
+	func() error { return nil },
+	func(result slimy.Result) error {
+		if resultStats != nil {
+			return json.NewEncoder(os.Stdout).Encode(rateResult(result))
+		}
+		return json.NewEncoder(os.Stdout).Encode(result)
+	},
+}
+
 func formatHuman(results []slimy.Result) error {
 	if len(results) > 0 {
 		if len(results) == 1 {
@@ -48,7 +120,22 @@ func formatHuman(results []slimy.Result) error {
 			}
 		}
 		for _, result := range results {
-			if _, err := fmt.Printf("(%6d, %6d) %3d chunks\n", result.X, result.Z, result.Count); err != nil {
+			var err error
+			if weightedMask {
+				_, err = fmt.Printf("(%6d, %6d) %7.2f score (%d chunks)", result.X, result.Z, result.Score, result.Count)
+			} else {
+				_, err = fmt.Printf("(%6d, %6d) %3d chunks", result.X, result.Z, result.Count)
+			}
+			if err != nil {
+				return err
+			}
+			if resultStats != nil {
+				rated := rateResult(result)
+				_, err = fmt.Printf(", p = %.3g, 1 in %.3g\n", rated.PValue, rated.Rarity)
+			} else {
+				_, err = fmt.Println()
+			}
+			if err != nil {
 				return err
 			}
 		}
@@ -61,21 +148,144 @@ func formatHuman(results []slimy.Result) error {
 }
 
 var fmter func([]slimy.Result) error
+var streamFmter *streamer // Set if results should be streamed rather than formatted at the end
+var resultLimit int
+var weightedMask bool               // Whether the mask image's brightness weights each chunk
+var resultStats *slimy.Distribution // Set if results should be shown with their rarity
+var statsOrder slimy.Order          // The order rarity is judged in
+
+// Returns a function that prints progress to stderr, counting in the given unit
+func progressPrinter(unit string) func(slimy.Progress) {
+	return func(p slimy.Progress) {
+		percent := 100.0
+		if p.Total > 0 {
+			percent = 100 * float64(p.Scanned) / float64(p.Total)
+		}
+		fmt.Fprintf(os.Stderr, "\r\x1b[K%5.1f%% (%d/%d %s) in %s, ETA %s",
+			percent, p.Scanned, p.Total, unit,
+			p.Elapsed.Round(time.Second), p.ETA().Round(time.Second),
+		)
+	}
+}
+
+// Returns a context that is cancelled when the process is interrupted
+func interruptContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt)
+	go func() {
+		select {
+		case <-sigCh:
+			cancel()
+		case <-ctx.Done():
+		}
+		signal.Stop(sigCh)
+	}()
+	return ctx, cancel
+}
+
+func runSearch(s slimy.Searcher, x0, z0, x1, z1 int32, threshold slimy.Threshold, worldSeed int64) (results []slimy.Result) {
+	// Stop the search on interrupt, so that partial results can be printed
+	ctx, cancel := interruptContext()
+	defer cancel()
+
+	opts := slimy.Options{Progress: progressPrinter("chunks"), Limit: resultLimit}
+	if clusterLink != nil {
+		// The limit applies to clusters, which can only be found from every result
+		opts.Limit = 0
+	}
+	var streamErr error
+	if streamFmter != nil {
+		if streamErr = streamFmter.header(); streamErr != nil {
+			log.Fatal(streamErr)
+		}
+		opts.Stream = func(result slimy.Result) {
+			// Stop searching if the output can no longer be written to
+			if streamErr == nil {
+				if streamErr = streamFmter.result(result); streamErr != nil {
+					cancel()
+				}
+			}
+		}
+	}
 
-func runSearch(s slimy.Searcher, x0, z0, x1, z1 int32, threshold int, worldSeed int64) (results []slimy.Result) {
 	fmt.Fprintf(os.Stderr, "Searching (%d, %d) to (%d, %d)\n", x0, z0, x1, z1)
 	start := time.Now()
-	results = s.Search(x0, z0, x1, z1, threshold, worldSeed)
+	results, err := s.SearchContext(ctx, x0, z0, x1, z1, threshold, worldSeed, opts)
 	end := time.Now()
-	fmt.Fprintf(os.Stderr, "Search finished in %s\n", end.Sub(start))
-	fmter(results)
+	fmt.Fprintln(os.Stderr)
+	if streamErr != nil {
+		log.Fatal(streamErr)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Search interrupted after %s, showing partial results\n", end.Sub(start))
+	} else {
+		fmt.Fprintf(os.Stderr, "Search finished in %s\n", end.Sub(start))
+	}
+	if streamFmter == nil {
+		outputResults(results, threshold)
+	}
 	return
 }
 
+// Writes the results of a search in the chosen format, clustering them if asked to
+func outputResults(results []slimy.Result, threshold slimy.Threshold) {
+	if clusterLink != nil {
+		clusters := slimy.ClusterResults(results, *clusterLink, threshold.Order())
+		if resultLimit > 0 && len(clusters) > resultLimit {
+			clusters = clusters[:resultLimit]
+		}
+		clusterFmter(clusters)
+	} else {
+		fmter(results)
+	}
+}
+
+// Parses a seed argument the way the game does, exiting if there is no seed to use.
+// The argument may also be a world folder, level.dat or server.properties to read the seed from.
+func parseSeedArg(s string) int64 {
+	if info, err := os.Stat(s); err == nil && (info.IsDir() || filepath.Ext(s) == ".dat" || filepath.Ext(s) == ".properties") {
+		seed, err := slimy.ReadWorldSeed(s)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Could not read seed:", err)
+			os.Exit(2)
+		}
+		fmt.Fprintf(os.Stderr, "Read seed %d from %s\n", seed, s)
+		return seed
+	}
+
+	seed, err := slimy.ParseSeed(s)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if slimy.IsTextSeed(s) {
+		fmt.Fprintf(os.Stderr, "Text seed %q is %d\n", strings.TrimSpace(s), seed)
+	}
+	return seed
+}
+
+// Chooses the output format, exiting if it isn't valid
+func setFormat(name string) {
+	switch name {
+	case "csv":
+		fmter, seedsFmter, blockFmter, clusterFmter = formatCSV, formatSeedsCSV, formatBlocksCSV, formatClustersCSV
+	case "json":
+		fmter, seedsFmter, blockFmter, clusterFmter = formatJSON, formatSeedsJSON, formatBlocksJSON, formatClustersJSON
+	case "ndjson":
+		fmter, seedsFmter, blockFmter, clusterFmter = formatNDJSON, formatSeedsNDJSON, formatBlocksNDJSON, formatClustersNDJSON
+	case "human":
+		fmter, seedsFmter, blockFmter, clusterFmter = formatHuman, formatSeedsHuman, formatBlocksHuman, formatClustersHuman
+	default:
+		fmt.Fprintln(os.Stderr, "Format must be one of: csv, json, ndjson, human")
+		os.Exit(2)
+	}
+}
+
 func parsePos(s string) (pos [2]int, err error) {
 	parts := strings.Split(s, ",")
 	if len(parts) != 2 {
-		return [2]int{}, errors.New("Position must be of the form 'X,Z')")
+		return [2]int{}, errors.New("Position must be of the form 'X,Z'")
 	}
 
 	for i := 0; i < 2; i++ {
@@ -87,68 +297,249 @@ func parsePos(s string) (pos [2]int, err error) {
 	return
 }
 
+// Parses a position argument, exiting if it isn't valid
+func parsePosArg(s string) [2]int {
+	pos, err := parsePos(s)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not parse position:", err)
+		os.Exit(2)
+	}
+	return pos
+}
+
+// Parses an edition name, exiting if it isn't valid
+func parseEditionArg(s string) slimy.Edition {
+	edition, err := slimy.ParseEdition(s)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	return edition
+}
+
+// Parses a threshold argument, exiting if it isn't valid
+func parseThresholdArg(s string) slimy.Threshold {
+	threshold, err := slimy.ParseThreshold(s)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not parse threshold:", err)
+		os.Exit(2)
+	}
+	return threshold
+}
+
+// Parses a search range argument, exiting if it isn't a non-negative 32 bit integer
+func parseRangeArg(s string) int64 {
+	searchRange, err := strconv.ParseInt(s, 10, 32)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not convert range to integer:", err)
+		os.Exit(2)
+	}
+	if searchRange < 0 {
+		fmt.Fprintln(os.Stderr, "Range must not be negative")
+		os.Exit(2)
+	}
+	return searchRange
+}
+
+// Returns the area within searchRange chunks of pos, exiting if any of it is past the edge of the coordinate range
+func searchArea(pos [2]int, searchRange int64) (x0, z0, x1, z1 int32) {
+	for _, c := range pos {
+		if searchRange > math.MaxInt32 || c < math.MinInt32 || c > math.MaxInt32 ||
+			int64(c)-searchRange < math.MinInt32 || int64(c)+searchRange > math.MaxInt32 {
+			fmt.Fprintln(os.Stderr, "The area extends past the edge of the coordinate range")
+			os.Exit(2)
+		}
+	}
+	r := int32(searchRange)
+	cx, cz := int32(pos[0]), int32(pos[1])
+	return cx - r, cz - r, cx + r, cz + r
+}
+
+// Subcommands, run as the first argument
+var commands = map[string]func(args []string){
+	"crack":       crackMain,
+	"mask":        maskMain,
+	"stats":       statsMain,
+	"render":      renderMain,
+	"serve-tiles": serveTilesMain,
+	"coordinate":  coordinateMain,
+	"worker":      workerMain,
+}
+
 func main() {
+	if len(os.Args) > 1 {
+		if cmd, ok := commands[os.Args[1]]; ok {
+			cmd(os.Args[2:])
+			return
+		}
+	}
+
 	workerCount := flag.Int("j", runtime.GOMAXPROCS(0), "number of concurrent workers (cpu only)")
-	outputFormat := flag.String("f", "human", "output `format` (valid options: csv, json, human)")
+	outputFormat := flag.String("f", "human", "output `format` (valid options: csv, json, ndjson, human)")
+	flag.IntVar(&resultLimit, "n", 0, "maximum `number` of results to output, keeping the best (0 for no limit)")
+	stream := flag.Bool("stream", false, "write results as soon as they are found, in no particular order (search mode only) (csv and ndjson formats only)")
 	method := flag.String("m", "gpu", "search method to use (search mode only) (options: cpu, gpu)")
-	mask := flag.String("mask", "", "mask image `file`name")
+	editionName := flag.String("edition", "java", "Minecraft `edition` (options: java, bedrock)")
+	afk := flag.Bool("afk", false, "find the best blocks to stand on, scoring slime chunks by how much of them is 24 to 128 blocks away (search mode only) (cpu only)")
+	seedFile := flag.String("seeds", "", "search every seed listed in `file` (- for stdin), one seed or start..end range per line (search mode only)")
+	mask := flag.String("mask", "", "mask image `file`name, or a mask spec such as annulus(1,8) (see the mask command)")
+	outward := flag.Bool("outward", false, "search rings of increasing size around the center, out to the range, and show the nearest results first (search mode only)")
+	deadline := flag.Duration("deadline", 0, "stop an outward search after this `duration`, showing what was found (0 for no deadline)")
+	checkpointFile := flag.String("checkpoint", "", "periodically save the progress of the search to `file` (search mode only) (cpu only)")
+	resume := flag.Bool("resume", false, "resume the search saved in the -checkpoint file, which must be for the same seed, area, threshold and mask")
+	showStats := flag.Bool("stats", false, "show the p-value and rarity of each result, assuming each chunk is a slime chunk with probability 1 in 10 (see the stats command) (not supported with -afk or -cluster)")
+	clusterSpec := flag.String("cluster", "", "group results whose centres are within `radius` chunks, or whose masks overlap if set to mask, and only output the best of each group (search mode only)")
+	flag.BoolVar(&weightedMask, "weighted", false, "weight each chunk of the mask by the brightness of its pixel, so results are scored rather than counted (not supported with -afk)")
 	pos := flag.String("pos", "0,0", "search center `position`")
 	vsync := flag.Bool("vsync", true, "enable vsync (gui mode only)")
 
 	flag.CommandLine.Usage = func() {
 		cmd := filepath.Base(os.Args[0])
 		fmt.Fprintf(os.Stderr, "Usage: %s [options] seed range threshold\n", cmd)
-		fmt.Fprintf(os.Stderr, "       %s [options] seed threshold\n\n", cmd)
+		fmt.Fprintf(os.Stderr, "       %s [options] seed threshold\n", cmd)
+		fmt.Fprintf(os.Stderr, "       %s [options] -seeds file range threshold\n", cmd)
+		fmt.Fprintf(os.Stderr, "       %s crack [options] [file]\n", cmd)
+		fmt.Fprintf(os.Stderr, "       %s mask [options] spec|file\n", cmd)
+		fmt.Fprintf(os.Stderr, "       %s stats [options] [seed] range\n", cmd)
+		fmt.Fprintf(os.Stderr, "       %s render [options] seed range\n", cmd)
+		fmt.Fprintf(os.Stderr, "       %s serve-tiles [options]\n", cmd)
+		fmt.Fprintf(os.Stderr, "       %s coordinate [options] seed range threshold\n", cmd)
+		fmt.Fprintf(os.Stderr, "       %s worker [options] url\n\n", cmd)
 		flag.PrintDefaults()
 		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "The seed may be a number or any text, which is turned into a number the same way as when creating a world")
+		fmt.Fprintln(os.Stderr, "It may also be a world folder, level.dat or server.properties file to read the seed from")
+		fmt.Fprintln(os.Stderr, "The seed is ignored for Bedrock Edition, since its slime chunks are the same in every world")
+		fmt.Fprintln(os.Stderr, "The threshold may be N or >=N (at least N chunks), <=N or -N (at most N chunks), N..M (between N and M chunks) or =N (exactly N chunks)")
+		fmt.Fprintln(os.Stderr, "With -weighted, the threshold applies to the score, which is the sum of the weights of the slime chunks, where a white pixel has weight 1")
 	}
 	flag.Parse()
 
-	switch *outputFormat {
-	case "csv":
-		fmter = formatCSV
-	case "json":
-		fmter = formatJSON
-	case "human":
-		fmter = formatHuman
-	default:
-		fmt.Fprintln(os.Stderr, "Format must be one of: csv, json, human")
+	setFormat(*outputFormat)
+
+	if *afk && (*stream || *seedFile != "" || *mask != "" || weightedMask) {
+		fmt.Fprintln(os.Stderr, "AFK search does not support streaming, multiple seeds or masks")
+		os.Exit(2)
+	}
+
+	if *outward && (*seedFile != "" || *afk || *clusterSpec != "") {
+		fmt.Fprintln(os.Stderr, "Outward search does not support multiple seeds, AFK search or clustering")
+		os.Exit(2)
+	}
+	if *deadline != 0 && !*outward {
+		fmt.Fprintln(os.Stderr, "A deadline can only be given for an outward search")
+		os.Exit(2)
+	}
+
+	if *resume && *checkpointFile == "" {
+		fmt.Fprintln(os.Stderr, "Resuming needs a -checkpoint file")
+		os.Exit(2)
+	}
+	if *checkpointFile != "" && (*method != "cpu" || *stream || *seedFile != "" || *afk || *outward) {
+		fmt.Fprintln(os.Stderr, "Checkpoints are only supported by the cpu method, and not with streaming, multiple seeds, AFK search or outward search")
 		os.Exit(2)
 	}
 
+	if *clusterSpec != "" && (*stream || *seedFile != "" || *afk) {
+		fmt.Fprintln(os.Stderr, "Clustering is not supported with streaming, multiple seeds or AFK search")
+		os.Exit(2)
+	}
+	if *showStats && (*afk || *clusterSpec != "") {
+		fmt.Fprintln(os.Stderr, "Statistics are not supported with AFK search or clustering")
+		os.Exit(2)
+	}
+
+	if *stream {
+		if *seedFile != "" {
+			fmt.Fprintln(os.Stderr, "Streaming is not supported when searching multiple seeds")
+			os.Exit(2)
+		}
+		switch *outputFormat {
+		case "csv":
+			streamFmter = &csvStreamer
+		case "ndjson":
+			streamFmter = &ndjsonStreamer
+		default:
+			fmt.Fprintln(os.Stderr, "Streaming is only supported for the csv and ndjson formats")
+			os.Exit(2)
+		}
+	}
+
 	var maskImg image.Image
 	if *mask == "" {
 		maskImg = util.GenDonut(1, 8)
 	} else {
-		f, err := os.Open(*mask)
+		var err error
+		maskImg, err = loadMask(*mask)
 		if err != nil {
 			log.Fatal(err)
 		}
-		maskImg, _, err = image.Decode(f)
-		f.Close()
+	}
+
+	if *clusterSpec != "" {
+		link, err := parseLinkage(*clusterSpec, maskImg)
 		if err != nil {
-			log.Fatal(err)
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
 		}
+		clusterLink = &link
 	}
 
-	centerPos, err := parsePos(*pos)
-	if err != nil {
-		log.Fatal(err)
+	edition := parseEditionArg(*editionName)
+	if edition == slimy.BedrockEdition && *seedFile != "" {
+		fmt.Fprintln(os.Stderr, "Bedrock Edition slime chunks don't depend on the seed, so there is no point searching multiple seeds")
+		os.Exit(2)
 	}
 
-	switch flag.NArg() {
+	centerPos := parsePosArg(*pos)
+
+	// With a seed file, the seed argument is left out
+	nargs := flag.NArg()
+	if *seedFile != "" {
+		nargs++
+	}
+
+	switch nargs {
 	default:
 		flag.CommandLine.Usage()
 		os.Exit(1)
 
 	case 3:
 		// Search mode
+		args := flag.Args()
+		var seeds []int64
+		var err error
+		if *seedFile != "" {
+			seeds, err = readSeedFile(*seedFile)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "Could not read seeds:", err)
+				os.Exit(2)
+			}
+		} else {
+			seeds = []int64{parseSeedArg(args[0])}
+			args = args[1:]
+		}
+
+		searchRange := parseRangeArg(args[0])
+		x0, z0, x1, z1 := searchArea(centerPos, searchRange)
+		threshold := parseThresholdArg(args[1])
+
+		if *showStats {
+			resultStats = slimy.NewDistribution(maskImg, weightedMask)
+			statsOrder = threshold.Order()
+		}
+
+		if *afk {
+			runAFKSearch(cpu.World{Seed: seeds[0], Edition: edition}, *workerCount, x0, z0, x1, z1, threshold)
+			return
+		}
+
 		var searcher slimy.Searcher
 		switch *method {
 		case "gpu":
-			searcher, err = gpu.NewSearcher(maskImg)
+			searcher, err = gpu.NewSearcher(maskImg, edition, weightedMask)
 		case "cpu":
-			searcher, err = cpu.NewSearcher(*workerCount, cpu.Mask{ORad: 8, IRad: 1})
+			searcher, err = cpu.NewSearcher(*workerCount, maskImg, edition, weightedMask)
 		}
 		if err != nil {
 			fmt.Fprintln(os.Stderr, err)
@@ -156,54 +547,25 @@ func main() {
 		}
 		defer searcher.Destroy()
 
-		// TODO: textual seeds
-		seed, err := strconv.ParseInt(flag.Arg(0), 10, 64)
-		if err != nil {
-			fmt.Fprintln(os.Stderr, "Could not convert seed to integer:", err)
-			os.Exit(2)
-		}
-
-		searchRange64, err := strconv.ParseInt(flag.Arg(1), 10, 32)
-		if err != nil {
-			fmt.Fprintln(os.Stderr, "Could not convert range to integer:", err)
-			os.Exit(2)
-		}
-		searchRange := int32(searchRange64)
-		if searchRange < 0 {
-			fmt.Fprintln(os.Stderr, "Range must not be negative")
-			os.Exit(2)
-		}
-
-		threshold64, err := strconv.ParseInt(flag.Arg(2), 10, 0)
-		if err != nil {
-			fmt.Fprintln(os.Stderr, "Could not convert threshold to integer:", err)
-			os.Exit(2)
+		if *seedFile != "" {
+			runSeedSearch(searcher, x0, z0, x1, z1, threshold, seeds)
+		} else if *checkpointFile != "" {
+			runCheckpointSearch(searcher.(*cpu.Searcher), x0, z0, x1, z1, threshold, seeds[0], *checkpointFile, *resume)
+		} else if *outward {
+			runOutwardSearch(searcher, int32(centerPos[0]), int32(centerPos[1]), int32(searchRange), threshold, seeds[0], *deadline)
+		} else {
+			runSearch(searcher, x0, z0, x1, z1, threshold, seeds[0])
 		}
-		threshold := int(threshold64)
-
-		runSearch(searcher,
-			int32(centerPos[0])-searchRange, int32(centerPos[1])-searchRange,
-			int32(centerPos[0])+searchRange, int32(centerPos[1])+searchRange,
-			threshold, seed,
-		)
 
 	case 2:
 		// GUI mode
+		streamFmter = nil // The GUI needs the full results to display them
 		// TODO: support CPU search
-		seed, err := strconv.ParseInt(flag.Arg(0), 10, 64)
-		if err != nil {
-			fmt.Fprintln(os.Stderr, "Could not convert seed to integer:", err)
-			os.Exit(2)
-		}
+		seed := parseSeedArg(flag.Arg(0))
 
-		threshold64, err := strconv.ParseInt(flag.Arg(1), 10, 0)
-		if err != nil {
-			fmt.Fprintln(os.Stderr, "Could not convert threshold to integer:", err)
-			os.Exit(2)
-		}
-		threshold := int(threshold64)
+		threshold := parseThresholdArg(flag.Arg(1))
 
-		app, err := NewApp(seed, threshold, centerPos, maskImg, *vsync)
+		app, err := NewApp(seed, edition, threshold, centerPos, maskImg, *vsync)
 		if err != nil {
 			fmt.Fprintln(os.Stderr, err)
 			os.Exit(2)
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/vktec/slimy/util"
+)
+
+// Loads a mask from an image file, or parses it as a mask spec if there is no such file
+func loadMask(name string) (image.Image, error) {
+	f, err := os.Open(name)
+	if os.IsNotExist(err) && strings.Contains(name, "(") {
+		return util.ParseMask(name)
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	img, _, err := image.Decode(f)
+	return img, err
+}
+
+const maskSpecHelp = `A mask spec describes a mask in chunks relative to its centre, using these shapes:
+  circle(r)                  cells within r of the centre
+  annulus(inner, outer)      cells more than inner and at most outer from the centre
+  rect(x0, z0, x1, z1)       cells between two corners, inclusive
+  polygon(x, z, x, z, ...)   cells inside or on the edges of a polygon
+  ascii("..#../.###./..#..") rows of cells separated by /, where # is in the mask and . is not
+  union(a, b, ...)           cells in any of the shapes
+  diff(a, b, ...)            cells in a but none of the others
+  translate(dx, dz, a)       a, moved away from the centre
+The default mask is annulus(1, 8)`
+
+func maskMain(args []string) {
+	flags := flag.NewFlagSet("mask", flag.ExitOnError)
+	output := flags.String("o", "", "write the mask to a PNG `file` instead of previewing it as text")
+	flags.Usage = func() {
+		cmd := filepath.Base(os.Args[0])
+		fmt.Fprintf(os.Stderr, "Usage: %s mask [options] spec|file\n\n", cmd)
+		fmt.Fprintln(os.Stderr, "Previews a mask, as given to -mask, and reports its size.")
+		fmt.Fprintln(os.Stderr, "In the text preview, # is in the mask and . is not. The centre, where results are reported, is @ if it is in the mask and + otherwise.")
+		fmt.Fprintln(os.Stderr)
+		flags.PrintDefaults()
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, maskSpecHelp)
+	}
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(1)
+	}
+
+	img, err := loadMask(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not load mask:", err)
+		os.Exit(2)
+	}
+
+	dim := img.Bounds().Canon()
+	cx, cz := dim.Min.X+dim.Dx()/2, dim.Min.Y+dim.Dy()/2
+	cells := 0
+	for y := dim.Min.Y; y < dim.Max.Y; y++ {
+		for x := dim.Min.X; x < dim.Max.X; x++ {
+			if util.InMask(img.At(x, y)) {
+				cells++
+			}
+		}
+	}
+	fmt.Fprintf(os.Stderr, "%d cells in a %dx%d mask, from (%d, %d) to (%d, %d) relative to the centre\n",
+		cells, dim.Dx(), dim.Dy(), dim.Min.X-cx, dim.Min.Y-cz, dim.Max.X-1-cx, dim.Max.Y-1-cz)
+
+	if *output != "" {
+		if err := writeMaskPNG(*output, img); err != nil {
+			fmt.Fprintln(os.Stderr, "Could not write mask:", err)
+			os.Exit(2)
+		}
+		return
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+	for y := dim.Min.Y; y < dim.Max.Y; y++ {
+		for x := dim.Min.X; x < dim.Max.X; x++ {
+			in := util.InMask(img.At(x, y))
+			switch {
+			case x == cx && y == cz && in:
+				w.WriteByte('@')
+			case x == cx && y == cz:
+				w.WriteByte('+')
+			case in:
+				w.WriteByte('#')
+			default:
+				w.WriteByte('.')
+			}
+		}
+		w.WriteByte('\n')
+	}
+	w.Flush()
+}
+
+// Writes the mask as a black and white PNG, which can be given back to -mask
+func writeMaskPNG(name string, img image.Image) error {
+	dim := img.Bounds().Canon()
+	out := image.NewGray(image.Rect(0, 0, dim.Dx(), dim.Dy()))
+	for y := dim.Min.Y; y < dim.Max.Y; y++ {
+		for x := dim.Min.X; x < dim.Max.X; x++ {
+			if util.InMask(img.At(x, y)) {
+				out.Pix[(y-dim.Min.Y)*out.Stride+x-dim.Min.X] = 0xff
+			}
+		}
+	}
+
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, out); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/vktec/slimy"
+)
+
+func runOutwardSearch(s slimy.Searcher, cx, cz, maxRadius int32, threshold slimy.Threshold, worldSeed int64, deadline time.Duration) {
+	// Stop the search on interrupt or at the deadline, so that partial results can be printed
+	ctx, cancel := interruptContext()
+	defer cancel()
+	if deadline > 0 {
+		ctx, cancel = context.WithTimeout(ctx, deadline)
+		defer cancel()
+	}
+
+	opts := slimy.Options{Progress: progressPrinter("chunks"), Limit: resultLimit}
+	var streamErr error
+	if streamFmter != nil {
+		if streamErr = streamFmter.header(); streamErr != nil {
+			log.Fatal(streamErr)
+		}
+		opts.Stream = func(result slimy.Result) {
+			// Stop searching if the output can no longer be written to
+			if streamErr == nil {
+				if streamErr = streamFmter.result(result); streamErr != nil {
+					cancel()
+				}
+			}
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "Searching outward from (%d, %d), up to %d chunks away\n", cx, cz, maxRadius)
+	start := time.Now()
+	results, radius, err := slimy.SearchOutward(ctx, s, cx, cz, maxRadius, threshold, worldSeed, opts)
+	end := time.Now()
+	fmt.Fprintln(os.Stderr)
+	if streamErr != nil {
+		log.Fatal(streamErr)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Search stopped after %s, showing partial results\n", end.Sub(start))
+	} else {
+		fmt.Fprintf(os.Stderr, "Search finished in %s\n", end.Sub(start))
+	}
+	if radius < 0 {
+		fmt.Fprintln(os.Stderr, "No ring was searched in full")
+	} else {
+		fmt.Fprintf(os.Stderr, "Searched every position up to %d chunks from the center\n", radius)
+	}
+	if streamFmter == nil {
+		fmter(results)
+	}
+}
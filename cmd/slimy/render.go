@@ -0,0 +1,384 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/vktec/slimy"
+	"github.com/vktec/slimy/cpu"
+	"github.com/vktec/slimy/util"
+)
+
+// The largest width or height of a rendered image, in pixels
+const maxRenderSize = 16384
+
+// The colours of a rendered map
+type palette struct {
+	background, slime, grid, text, overlay, result, center color.RGBA
+}
+
+var palettes = map[string]palette{
+	"dark": {
+		hexColor(0x000000), hexColor(0x64ff64), hexColor(0x404040), hexColor(0xffffff),
+		hexColor(0xff4040), hexColor(0xffff00), hexColor(0x40a0ff),
+	},
+	"light": {
+		hexColor(0xffffff), hexColor(0x2e9e2e), hexColor(0xc0c0c0), hexColor(0x000000),
+		hexColor(0xff6060), hexColor(0xa000a0), hexColor(0x0060ff),
+	},
+	"print": {
+		hexColor(0xffffff), hexColor(0x909090), hexColor(0xd0d0d0), hexColor(0x000000),
+		hexColor(0x000000), hexColor(0x000000), hexColor(0x000000),
+	},
+}
+
+func hexColor(c uint32) color.RGBA {
+	return color.RGBA{uint8(c >> 16), uint8(c >> 8), uint8(c), 255}
+}
+
+// Parses a palette name, or a comma-separated list of hex colours replacing those of the dark palette in order
+func parsePalette(s string) (palette, error) {
+	if p, ok := palettes[s]; ok {
+		return p, nil
+	}
+	p := palettes["dark"]
+	fields := []*color.RGBA{&p.background, &p.slime, &p.grid, &p.text, &p.overlay, &p.result, &p.center}
+	parts := strings.Split(s, ",")
+	if len(parts) > len(fields) {
+		return palette{}, fmt.Errorf("Palette has %d colours, but there are only %d", len(parts), len(fields))
+	}
+	for i, part := range parts {
+		part = strings.TrimPrefix(strings.TrimSpace(part), "#")
+		c, err := strconv.ParseUint(part, 16, 32)
+		if err != nil || len(part) != 6 {
+			return palette{}, fmt.Errorf("Palette must be one of dark, light or print, or a list of colours such as #000000,#64ff64, not %q", s)
+		}
+		*fields[i] = hexColor(uint32(c))
+	}
+	return p, nil
+}
+
+// Glyphs for axis labels, 3 pixels wide and 5 tall
+var glyphs = map[rune][5]string{
+	'0': {"###", "#.#", "#.#", "#.#", "###"},
+	'1': {".#.", "##.", ".#.", ".#.", "###"},
+	'2': {"###", "..#", "###", "#..", "###"},
+	'3': {"###", "..#", "###", "..#", "###"},
+	'4': {"#.#", "#.#", "###", "..#", "..#"},
+	'5': {"###", "#..", "###", "..#", "###"},
+	'6': {"###", "#..", "###", "#.#", "###"},
+	'7': {"###", "..#", "..#", "..#", "..#"},
+	'8': {"###", "#.#", "###", "#.#", "###"},
+	'9': {"###", "#.#", "###", "..#", "###"},
+	'-': {"...", "...", "###", "...", "..."},
+}
+
+const (
+	glyphScale   = 2 // Pixels per glyph pixel
+	glyphAdvance = 4 * glyphScale
+	glyphHeight  = 5 * glyphScale
+	labelPadding = 4
+)
+
+func textWidth(s string) int {
+	return len(s)*glyphAdvance - glyphScale
+}
+
+// Draws text with its top left corner at x, y
+func drawText(img draw.Image, x, y int, s string, c color.Color) {
+	for i, r := range s {
+		for gy, row := range glyphs[r] {
+			for gx, pixel := range row {
+				if pixel == '#' {
+					rect := image.Rect(0, 0, glyphScale, glyphScale).Add(image.Pt(x+i*glyphAdvance+gx*glyphScale, y+gy*glyphScale))
+					draw.Draw(img, rect, image.NewUniform(c), image.Point{}, draw.Src)
+				}
+			}
+		}
+	}
+}
+
+// Blends a colour over a rectangle of an image, by the given fraction
+func blend(img *image.RGBA, rect image.Rectangle, c color.RGBA, fraction float64) {
+	rect = rect.Intersect(img.Bounds())
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		for x := rect.Min.X; x < rect.Max.X; x++ {
+			old := img.RGBAAt(x, y)
+			mix := func(a, b uint8) uint8 { return uint8(float64(a)*(1-fraction) + float64(b)*fraction + 0.5) }
+			img.SetRGBA(x, y, color.RGBA{mix(old.R, c.R), mix(old.G, c.G), mix(old.B, c.B), 255})
+		}
+	}
+}
+
+// Picks the spacing of axis labels, in chunks: a multiple of the grid spacing if there is one, or else a round number
+func labelStep(grid, scale, minPixels int) int {
+	base := 1
+	if grid > 0 {
+		base = grid
+	}
+	for mag := 1; ; mag *= 10 {
+		for _, k := range []int{1, 2, 5} {
+			if step := base * k * mag; step*scale >= minPixels {
+				return step
+			}
+		}
+	}
+}
+
+// Returns the first multiple of step at or after n
+func ceilMultiple(n, step int) int {
+	m := n / step * step
+	if m < n {
+		m += step
+	}
+	return m
+}
+
+// Draws a map of slime chunks, along with any results and their masks
+type renderer struct {
+	img       *image.RGBA
+	pal       palette
+	scale     int
+	x0, z0    int32
+	x1, z1    int32
+	mapOrigin image.Point // Where the corner of chunk x0, z0 is drawn
+}
+
+// Returns the pixels covered by a chunk
+func (r *renderer) chunkRect(x, z int32) image.Rectangle {
+	p := r.mapOrigin.Add(image.Pt(int(x-r.x0)*r.scale, int(z-r.z0)*r.scale))
+	return image.Rectangle{p, p.Add(image.Pt(r.scale, r.scale))}
+}
+
+func (r *renderer) inArea(x, z int32) bool {
+	return r.x0 <= x && x < r.x1 && r.z0 <= z && z < r.z1
+}
+
+func (r *renderer) drawChunks(world cpu.World, workerCount int) {
+	chunks := image.NewRGBA(image.Rect(int(r.x0), int(r.z0), int(r.x1), int(r.z1)))
+	world.DrawAreaColors(workerCount, chunks, r.pal.background, r.pal.slime)
+	for py := 0; py < int(r.z1-r.z0)*r.scale; py++ {
+		z := int(r.z0) + py/r.scale
+		for px := 0; px < int(r.x1-r.x0)*r.scale; px++ {
+			x := int(r.x0) + px/r.scale
+			r.img.SetRGBA(r.mapOrigin.X+px, r.mapOrigin.Y+py, chunks.RGBAAt(x, z))
+		}
+	}
+}
+
+// Draws lines along the edges of chunks whose coordinates are multiples of grid
+func (r *renderer) drawGrid(grid int) {
+	mapRect := r.chunkRect(r.x0, r.z0).Union(r.chunkRect(r.x1-1, r.z1-1))
+	for x := ceilMultiple(int(r.x0), grid); x <= int(r.x1); x += grid {
+		px := r.chunkRect(int32(x), r.z0).Min.X
+		draw.Draw(r.img, image.Rect(px, mapRect.Min.Y, px+1, mapRect.Max.Y), image.NewUniform(r.pal.grid), image.Point{}, draw.Src)
+	}
+	for z := ceilMultiple(int(r.z0), grid); z <= int(r.z1); z += grid {
+		py := r.chunkRect(r.x0, int32(z)).Min.Y
+		draw.Draw(r.img, image.Rect(mapRect.Min.X, py, mapRect.Max.X, py+1), image.NewUniform(r.pal.grid), image.Point{}, draw.Src)
+	}
+}
+
+// Labels the edges of chunks whose coordinates are multiples of step, with X along the top and Z down the left
+func (r *renderer) drawLabels(step int) {
+	for x := ceilMultiple(int(r.x0), step); x <= int(r.x1); x += step {
+		s := strconv.Itoa(x)
+		px := r.chunkRect(int32(x), r.z0).Min.X
+		drawText(r.img, px-textWidth(s)/2, labelPadding, s, r.pal.text)
+	}
+	for z := ceilMultiple(int(r.z0), step); z <= int(r.z1); z += step {
+		s := strconv.Itoa(z)
+		py := r.chunkRect(r.x0, int32(z)).Min.Y
+		drawText(r.img, r.mapOrigin.X-labelPadding-textWidth(s), py-glyphHeight/2, s, r.pal.text)
+	}
+}
+
+// Shades the chunks covered by each result's mask, and marks each result's centre
+func (r *renderer) drawResults(results []slimy.Result, mask image.Image) {
+	offsets := util.MaskOffsets(mask, weightedMask)
+
+	// Shade each chunk once, even where masks overlap
+	covered := make(map[image.Point]bool)
+	for _, result := range results {
+		for _, off := range offsets {
+			x, z := result.X+int32(off.X), result.Z+int32(off.Y)
+			p := image.Pt(int(x), int(z))
+			if r.inArea(x, z) && !covered[p] {
+				covered[p] = true
+				blend(r.img, r.chunkRect(x, z), r.pal.overlay, 0.4)
+			}
+		}
+	}
+	for _, result := range results {
+		rect := r.chunkRect(result.X, result.Z)
+		// Leave a border at larger scales, so the chunk underneath can still be seen
+		if inset := r.scale / 4; inset > 0 {
+			rect = rect.Inset(inset)
+		}
+		draw.Draw(r.img, rect, image.NewUniform(r.pal.result), image.Point{}, draw.Src)
+	}
+}
+
+// Draws a cross on a chunk
+func (r *renderer) drawCenter(x, z int32) {
+	rect := r.chunkRect(x, z)
+	mid := rect.Min.Add(image.Pt(r.scale/2, r.scale/2))
+	arm := 2*r.scale + 4
+	thick := 1 + r.scale/4
+	c := image.NewUniform(r.pal.center)
+	draw.Draw(r.img, image.Rect(mid.X-arm, mid.Y-thick/2, mid.X+arm+1, mid.Y-thick/2+thick), c, image.Point{}, draw.Src)
+	draw.Draw(r.img, image.Rect(mid.X-thick/2, mid.Y-arm, mid.X-thick/2+thick, mid.Y+arm+1), c, image.Point{}, draw.Src)
+}
+
+func renderMain(args []string) {
+	flags := flag.NewFlagSet("render", flag.ExitOnError)
+	workerCount := flags.Int("j", runtime.GOMAXPROCS(0), "number of concurrent workers")
+	output := flags.String("o", "slimy.png", "PNG `file` to write, or - for stdout")
+	scale := flags.Int("scale", 4, "`pixels` per chunk")
+	grid := flags.Int("grid", 0, "draw grid lines every `n` chunks, such as 32 for region files (0 for none)")
+	labels := flags.Bool("labels", true, "label the axes with chunk coordinates")
+	paletteName := flags.String("palette", "dark", "`colours` to use: dark, light or print, or a comma-separated list of hex colours for the background, slime chunks, grid, labels, mask overlay, results and centre")
+	thresholdSpec := flags.String("results", "", "search the area and overlay results meeting this `threshold`, with their masks")
+	flags.IntVar(&resultLimit, "n", 0, "maximum `number` of results to overlay, keeping the best (0 for no limit)")
+	mask := flags.String("mask", "", "mask image `file`name, or a mask spec such as annulus(1,8) (see the mask command)")
+	flags.BoolVar(&weightedMask, "weighted", false, "weight each chunk of the mask by the brightness of its pixel, so results are scored rather than counted")
+	editionName := flags.String("edition", "java", "Minecraft `edition` (options: java, bedrock)")
+	pos := flags.String("pos", "0,0", "center `position`")
+	markCenter := flags.Bool("mark-center", true, "mark the center position")
+	flags.Usage = func() {
+		cmd := filepath.Base(os.Args[0])
+		fmt.Fprintf(os.Stderr, "Usage: %s render [options] seed range\n\n", cmd)
+		fmt.Fprintln(os.Stderr, "Draws a map of the slime chunks within range of the center, using the CPU, and writes it as a PNG image.")
+		fmt.Fprintln(os.Stderr)
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 2 {
+		flags.Usage()
+		os.Exit(1)
+	}
+
+	pal, err := parsePalette(*paletteName)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if *scale <= 0 || *grid < 0 {
+		fmt.Fprintln(os.Stderr, "Scale must be positive, and grid must not be negative")
+		os.Exit(2)
+	}
+	edition := parseEditionArg(*editionName)
+	centerPos := parsePosArg(*pos)
+	seed := parseSeedArg(flags.Arg(0))
+	searchRange := parseRangeArg(flags.Arg(1))
+	if searchRange == 0 {
+		fmt.Fprintln(os.Stderr, "Range must be positive")
+		os.Exit(2)
+	}
+	if size := 2 * searchRange * int64(*scale); size > maxRenderSize {
+		fmt.Fprintf(os.Stderr, "The image would be %d pixels across, more than the maximum of %d\n", size, maxRenderSize)
+		os.Exit(2)
+	}
+
+	r := &renderer{pal: pal, scale: *scale}
+	r.x0, r.z0, r.x1, r.z1 = searchArea(centerPos, searchRange)
+	mapSize := image.Pt(int(r.x1-r.x0)**scale, int(r.z1-r.z0)**scale)
+
+	// Leave room around the map for labels, including ones that stick out past its far edges
+	var margin image.Rectangle // Min is the top left margin, and Max the bottom right
+	step := 0
+	if *labels {
+		widest := textWidth(strconv.Itoa(int(r.x0)))
+		for _, n := range []int32{r.x1, r.z0, r.z1} {
+			if w := textWidth(strconv.Itoa(int(n))); w > widest {
+				widest = w
+			}
+		}
+		step = labelStep(*grid, *scale, widest+4*labelPadding)
+		margin = image.Rectangle{
+			image.Pt(widest+2*labelPadding, glyphHeight+2*labelPadding),
+			image.Pt(widest/2+labelPadding, glyphHeight/2+labelPadding),
+		}
+	}
+	r.mapOrigin = margin.Min
+	r.img = image.NewRGBA(image.Rectangle{image.Point{}, mapSize.Add(margin.Min).Add(margin.Max)})
+	draw.Draw(r.img, r.img.Bounds(), image.NewUniform(pal.background), image.Point{}, draw.Src)
+
+	world := cpu.World{Seed: seed, Edition: edition}
+	fmt.Fprintf(os.Stderr, "Drawing (%d, %d) to (%d, %d)\n", r.x0, r.z0, r.x1, r.z1)
+	r.drawChunks(world, *workerCount)
+	if *grid > 0 {
+		r.drawGrid(*grid)
+	}
+	if *labels {
+		r.drawLabels(step)
+	}
+
+	if *thresholdSpec != "" {
+		threshold := parseThresholdArg(*thresholdSpec)
+		maskImg := util.GenDonut(1, 8)
+		if *mask != "" {
+			var err error
+			maskImg, err = loadMask(*mask)
+			if err != nil {
+				log.Fatal(err)
+			}
+		}
+		searcher, err := cpu.NewSearcher(*workerCount, maskImg, edition, weightedMask)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		defer searcher.Destroy()
+
+		// Stop the search on interrupt, so that the results found so far can be drawn
+		ctx, cancel := interruptContext()
+		defer cancel()
+
+		opts := slimy.Options{Progress: progressPrinter("chunks"), Limit: resultLimit}
+		results, err := searcher.SearchContext(ctx, r.x0, r.z0, r.x1, r.z1, threshold, seed, opts)
+		fmt.Fprintln(os.Stderr)
+		if ctx.Err() != nil {
+			fmt.Fprintf(os.Stderr, "Search interrupted, drawing %d results found so far\n", len(results))
+		} else if err != nil {
+			fmt.Fprintln(os.Stderr, "Search failed:", err)
+			os.Exit(2)
+		} else {
+			fmt.Fprintf(os.Stderr, "Found %d results\n", len(results))
+		}
+		r.drawResults(results, maskImg)
+	}
+	if *markCenter {
+		r.drawCenter(int32(centerPos[0]), int32(centerPos[1]))
+	}
+
+	if err := writeRender(*output, r.img); err != nil {
+		fmt.Fprintln(os.Stderr, "Could not write image:", err)
+		os.Exit(2)
+	}
+}
+
+func writeRender(name string, img image.Image) error {
+	if name == "-" {
+		return png.Encode(os.Stdout, img)
+	}
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vktec/slimy"
+)
+
+// Reads seeds from r, one per line.
+// A line may also hold an inclusive range of seeds, written as start..end.
+// Blank lines and lines starting with # are ignored.
+func readSeeds(r io.Reader) (seeds []int64, err error) {
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		if i := strings.Index(text, ".."); i >= 0 {
+			start, err := strconv.ParseInt(strings.TrimSpace(text[:i]), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+			end, err := strconv.ParseInt(strings.TrimSpace(text[i+2:]), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+			rangeSeeds, err := slimy.SeedRange(start, end)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+			seeds = append(seeds, rangeSeeds...)
+		} else {
+			seed, err := strconv.ParseInt(text, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+			seeds = append(seeds, seed)
+		}
+		if len(seeds) > slimy.MaxSeeds {
+			return nil, fmt.Errorf("line %d: more than %d seeds", line, slimy.MaxSeeds)
+		}
+	}
+	return seeds, scanner.Err()
+}
+
+// Reads seeds from the named file, or from stdin if the name is -
+func readSeedFile(name string) ([]int64, error) {
+	if name == "-" {
+		return readSeeds(os.Stdin)
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return readSeeds(f)
+}
+
+func formatSeedsCSV(results []slimy.SeedResults) error {
+	if _, err := fmt.Print("Seed,"); err != nil {
+		return err
+	}
+	if err := csvStreamer.header(); err != nil {
+		return err
+	}
+	for _, seedResults := range results {
+		for _, result := range seedResults.Results {
+			if _, err := fmt.Print(seedResults.Seed, ","); err != nil {
+				return err
+			}
+			if err := csvStreamer.result(result); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func formatSeedsJSON(results []slimy.SeedResults) error {
+	if resultStats != nil {
+		type ratedSeedResults struct {
+			Seed    int64
+			Results []ratedResult
+		}
+		rated := make([]ratedSeedResults, len(results))
+		for i, seedResults := range results {
+			rated[i] = ratedSeedResults{seedResults.Seed, make([]ratedResult, len(seedResults.Results))}
+			for j, result := range seedResults.Results {
+				rated[i].Results[j] = rateResult(result)
+			}
+		}
+		return json.NewEncoder(os.Stdout).Encode(rated)
+	}
+	return json.NewEncoder(os.Stdout).Encode(results)
+}
+
+func formatSeedsNDJSON(results []slimy.SeedResults) error {
+	enc := json.NewEncoder(os.Stdout)
+	for _, seedResults := range results {
+		for _, result := range seedResults.Results {
+			var err error
+			if resultStats != nil {
+				err = enc.Encode(struct {
+					Seed int64
+					ratedResult
+				}{seedResults.Seed, rateResult(result)})
+			} else {
+				err = enc.Encode(struct {
+					Seed int64
+					slimy.Result
+				}{seedResults.Seed, result})
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func formatSeedsHuman(results []slimy.SeedResults) error {
+	for _, seedResults := range results {
+		if _, err := fmt.Printf("Seed %d: ", seedResults.Seed); err != nil {
+			return err
+		}
+		if err := formatHuman(seedResults.Results); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var seedsFmter func([]slimy.SeedResults) error
+
+func runSeedSearch(s slimy.Searcher, x0, z0, x1, z1 int32, threshold slimy.Threshold, worldSeeds []int64) {
+	// Stop the search on interrupt, so that partial results can be printed
+	ctx, cancel := interruptContext()
+	defer cancel()
+
+	// Only show the best result for each seed, unless asked for more
+	limit := resultLimit
+	if limit <= 0 {
+		limit = 1
+	}
+	opts := slimy.Options{Progress: progressPrinter("chunks"), Limit: limit}
+
+	fmt.Fprintf(os.Stderr, "Searching %d seeds from (%d, %d) to (%d, %d)\n", len(worldSeeds), x0, z0, x1, z1)
+	start := time.Now()
+	results, err := s.SearchSeeds(ctx, x0, z0, x1, z1, threshold, worldSeeds, opts)
+	end := time.Now()
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Search interrupted after %s, showing partial results\n", end.Sub(start))
+	} else {
+		fmt.Fprintf(os.Stderr, "Search finished in %s\n", end.Sub(start))
+	}
+	seedsFmter(results)
+}
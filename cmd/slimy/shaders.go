@@ -35,7 +35,7 @@ layout(binding = 0) uniform sampler2DRect mask;
 out vec4 color;
 void main() {
 ` + gpu.Coord + `
-	if (texelFetch(mask, ivec2(coord - origin)).r >= 0.5) discard;
+	if (texelFetch(mask, ivec2(coord - origin)).r > 0.0) discard;
 	color = vec4(0, 0, 0, 0.8);
 }
 `
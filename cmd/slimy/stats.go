@@ -0,0 +1,286 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"image"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"github.com/vktec/slimy"
+	"github.com/vktec/slimy/cpu"
+	"github.com/vktec/slimy/gpu"
+	"github.com/vktec/slimy/util"
+)
+
+// A result with its rarity, for output with -stats
+type ratedResult struct {
+	slimy.Result
+	PValue float64 // The chance of a random position scoring at least as well
+	Rarity float64 // 1 / PValue
+}
+
+func rateResult(result slimy.Result) ratedResult {
+	p := resultStats.PValue(result.Score, statsOrder)
+	// JSON can't encode infinity, which a p-value too small for a float64 would give
+	return ratedResult{result, p, math.Min(1/p, math.MaxFloat64)}
+}
+
+// A row of the stats command's histogram, covering scores from Score up to but not including Score + 1
+type statsRow struct {
+	Score     float64
+	Positions *int64   `json:",omitempty"` // The number of searched positions with a score in this range
+	Fraction  *float64 `json:",omitempty"` // The fraction of searched positions with a score in this range
+	Expected  float64  // The expected fraction of positions with a score in this range
+	PValue    float64  // The chance of a random position scoring at least Score
+	Rarity    float64  // 1 / PValue
+}
+
+// The stats command's output
+type statsReport struct {
+	Cells         int // The number of chunks in the mask
+	MaxScore      float64
+	Mean          float64
+	Positions     int64 // The number of positions in the area
+	ExpectedBest  float64
+	ExpectedWorst float64
+	Best          *float64 `json:",omitempty"` // The best score in the searched area
+	Worst         *float64 `json:",omitempty"` // The worst score in the searched area
+	Histogram     []statsRow
+}
+
+// Summarises a distribution, and a histogram of searched scores if it is not nil
+func makeStatsReport(mask image.Image, d *slimy.Distribution, hist *slimy.Histogram, positions int64) statsReport {
+	dim := mask.Bounds()
+	cells := 0
+	for y := dim.Min.Y; y < dim.Max.Y; y++ {
+		for x := dim.Min.X; x < dim.Max.X; x++ {
+			if weightedMask && util.MaskWeight(mask.At(x, y)) > 0 || !weightedMask && util.InMask(mask.At(x, y)) {
+				cells++
+			}
+		}
+	}
+	report := statsReport{
+		cells, d.Max(), d.Mean(), positions,
+		d.ExpectedBest(positions, slimy.Descending), d.ExpectedBest(positions, slimy.Ascending),
+		nil, nil, nil,
+	}
+	if hist != nil && hist.Total > 0 {
+		best, worst := hist.Best(slimy.Descending), hist.Best(slimy.Ascending)
+		report.Best, report.Worst = &best, &worst
+	}
+
+	// Group units into whole scores, which only makes a difference for weighted masks
+	var expected []float64
+	var observed []int64
+	for i, p := range d.P {
+		score := int(float64(i) * d.Unit)
+		if score >= len(expected) {
+			expected = append(expected, 0)
+			observed = append(observed, 0)
+		}
+		expected[score] += p
+		if hist != nil && i < len(hist.Counts) {
+			observed[score] += hist.Counts[i]
+		}
+	}
+
+	for score := range expected {
+		// Leave out scores that are neither seen nor likely to be seen
+		if observed[score] == 0 && expected[score]*float64(positions) < 0.01 {
+			continue
+		}
+		p := d.PValue(float64(score), slimy.Descending)
+		row := statsRow{Score: float64(score), Expected: expected[score], PValue: p, Rarity: math.Min(1/p, math.MaxFloat64)}
+		if hist != nil {
+			n := observed[score]
+			fraction := float64(n) / float64(hist.Total)
+			row.Positions, row.Fraction = &n, &fraction
+		}
+		report.Histogram = append(report.Histogram, row)
+	}
+	return report
+}
+
+func formatStatsHuman(r statsReport) error {
+	unit := "chunks"
+	if weightedMask {
+		unit = "score"
+	}
+	if _, err := fmt.Printf("Mask of %d chunks, scoring from 0 to %g with a mean of %.2f\n", r.Cells, r.MaxScore, r.Mean); err != nil {
+		return err
+	}
+	if _, err := fmt.Printf("Expected best of %d positions: %.2f %s\n", r.Positions, r.ExpectedBest, unit); err != nil {
+		return err
+	}
+	if _, err := fmt.Printf("Expected worst of %d positions: %.2f %s\n", r.Positions, r.ExpectedWorst, unit); err != nil {
+		return err
+	}
+	if r.Best != nil {
+		if _, err := fmt.Printf("Searched best: %g %s, worst: %g %s\n", *r.Best, unit, *r.Worst, unit); err != nil {
+			return err
+		}
+	}
+	if _, err := fmt.Println(); err != nil {
+		return err
+	}
+
+	header := fmt.Sprintf("%7s %12s", "Score", "Expected")
+	if r.Best != nil {
+		header = fmt.Sprintf("%7s %12s %12s %12s", "Score", "Positions", "Fraction", "Expected")
+	}
+	if _, err := fmt.Printf("%s %12s %12s\n", header, "P(>= score)", "Rarity"); err != nil {
+		return err
+	}
+	for _, row := range r.Histogram {
+		var err error
+		if row.Positions != nil {
+			_, err = fmt.Printf("%7g %12d %12.3g %12.3g", row.Score, *row.Positions, *row.Fraction, row.Expected)
+		} else {
+			_, err = fmt.Printf("%7g %12.3g", row.Score, row.Expected)
+		}
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Printf(" %12.3g %12.3g\n", row.PValue, row.Rarity); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func formatStatsCSV(r statsReport) error {
+	header := "Score,Expected Fraction,P-Value,Rarity"
+	if r.Best != nil {
+		header = "Score,Positions,Fraction,Expected Fraction,P-Value,Rarity"
+	}
+	if _, err := fmt.Println(header); err != nil {
+		return err
+	}
+	for _, row := range r.Histogram {
+		if _, err := fmt.Print(row.Score, ","); err != nil {
+			return err
+		}
+		if row.Positions != nil {
+			if _, err := fmt.Print(*row.Positions, ",", formatScore(*row.Fraction), ","); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Print(formatScore(row.Expected), ",", formatScore(row.PValue), ",", formatScore(row.Rarity), "\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func formatStatsJSON(r statsReport) error {
+	return json.NewEncoder(os.Stdout).Encode(r)
+}
+
+func statsMain(args []string) {
+	flags := flag.NewFlagSet("stats", flag.ExitOnError)
+	workerCount := flags.Int("j", runtime.GOMAXPROCS(0), "number of concurrent workers (cpu only)")
+	method := flags.String("m", "gpu", "search method to use (options: cpu, gpu)")
+	outputFormat := flags.String("f", "human", "output `format` (valid options: csv, json, human)")
+	editionName := flags.String("edition", "java", "Minecraft `edition` (options: java, bedrock)")
+	mask := flags.String("mask", "", "mask image `file`name, or a mask spec such as annulus(1,8) (see the mask command)")
+	flags.BoolVar(&weightedMask, "weighted", false, "weight each chunk of the mask by the brightness of its pixel, so results are scored rather than counted")
+	pos := flags.String("pos", "0,0", "search center `position`")
+	flags.Usage = func() {
+		cmd := filepath.Base(os.Args[0])
+		fmt.Fprintf(os.Stderr, "Usage: %s stats [options] [seed] range\n\n", cmd)
+		fmt.Fprintln(os.Stderr, "Shows how the mask's score is distributed, assuming each chunk is a slime chunk with probability 1 in 10, and the best and worst scores to expect in an area of the given range.")
+		fmt.Fprintln(os.Stderr, "If a seed is given, the area is searched and a histogram of its scores is shown alongside.")
+		fmt.Fprintln(os.Stderr, "Nearby positions share chunks, so the expected best and worst are only estimates.")
+		fmt.Fprintln(os.Stderr)
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 1 && flags.NArg() != 2 {
+		flags.Usage()
+		os.Exit(1)
+	}
+
+	var format func(statsReport) error
+	switch *outputFormat {
+	case "csv":
+		format = formatStatsCSV
+	case "json":
+		format = formatStatsJSON
+	case "human":
+		format = formatStatsHuman
+	default:
+		fmt.Fprintln(os.Stderr, "Format must be one of: csv, json, human")
+		os.Exit(2)
+	}
+
+	var maskImg image.Image
+	if *mask == "" {
+		maskImg = util.GenDonut(1, 8)
+	} else {
+		var err error
+		maskImg, err = loadMask(*mask)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	edition := parseEditionArg(*editionName)
+	centerPos := parsePosArg(*pos)
+	searchRange := parseRangeArg(flags.Arg(flags.NArg() - 1))
+	if searchRange == 0 {
+		fmt.Fprintln(os.Stderr, "Range must be positive")
+		os.Exit(2)
+	}
+	x0, z0, x1, z1 := searchArea(centerPos, searchRange)
+	positions := int64(x1-x0) * int64(z1-z0)
+
+	dist := slimy.NewDistribution(maskImg, weightedMask)
+	var hist *slimy.Histogram
+	if flags.NArg() == 2 {
+		seed := parseSeedArg(flags.Arg(0))
+
+		var searcher slimy.Searcher
+		var err error
+		switch *method {
+		case "gpu":
+			searcher, err = gpu.NewSearcher(maskImg, edition, weightedMask)
+		case "cpu":
+			searcher, err = cpu.NewSearcher(*workerCount, maskImg, edition, weightedMask)
+		default:
+			fmt.Fprintln(os.Stderr, "Method must be one of: cpu, gpu")
+			os.Exit(2)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		defer searcher.Destroy()
+
+		// Stop the search on interrupt, so that the partial histogram can be printed
+		ctx, cancel := interruptContext()
+		defer cancel()
+
+		// Every position is needed, so stream them into the histogram rather than collecting them
+		hist = dist.NewHistogram()
+		opts := slimy.Options{Progress: progressPrinter("chunks"), Stream: hist.Add}
+		fmt.Fprintf(os.Stderr, "Searching (%d, %d) to (%d, %d)\n", x0, z0, x1, z1)
+		start := time.Now()
+		_, err = searcher.SearchContext(ctx, x0, z0, x1, z1, slimy.Threshold{}, seed, opts)
+		end := time.Now()
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Search interrupted after %s, showing partial results\n", end.Sub(start))
+		} else {
+			fmt.Fprintf(os.Stderr, "Search finished in %s\n", end.Sub(start))
+		}
+	}
+
+	if err := format(makeStatsReport(maskImg, dist, hist, positions)); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+
+	"github.com/vktec/slimy"
+	"github.com/vktec/slimy/cpu"
+	"github.com/vktec/slimy/tiles"
+	"github.com/vktec/slimy/util"
+)
+
+func serveTilesMain(args []string) {
+	flags := flag.NewFlagSet("serve-tiles", flag.ExitOnError)
+	workerCount := flags.Int("j", runtime.GOMAXPROCS(0), "number of concurrent workers")
+	listen := flags.String("listen", "127.0.0.1:8080", "`address` to listen on")
+	cacheSize := flags.Int("cache", 4096, "maximum `number` of tiles to keep in memory (0 to disable the cache)")
+	editionName := flags.String("edition", "java", "Minecraft `edition` (options: java, bedrock)")
+	seedArg := flags.String("seed", "", "`seed` for the viewer to show, and to search with -results")
+	thresholdSpec := flags.String("results", "", "search around the center and overlay results meeting this `threshold`, with their masks (requires -seed for Java Edition)")
+	searchRange := flags.Int("range", 1000, "search `range` in chunks for -results")
+	flags.IntVar(&resultLimit, "n", 0, "maximum `number` of results to overlay, keeping the best (0 for no limit)")
+	mask := flags.String("mask", "", "mask image `file`name, or a mask spec such as annulus(1,8) (see the mask command)")
+	flags.BoolVar(&weightedMask, "weighted", false, "weight each chunk of the mask by the brightness of its pixel, so results are scored rather than counted")
+	pos := flags.String("pos", "0,0", "search center `position`")
+	flags.Usage = func() {
+		cmd := filepath.Base(os.Args[0])
+		fmt.Fprintf(os.Stderr, "Usage: %s serve-tiles [options]\n\n", cmd)
+		fmt.Fprintln(os.Stderr, "Serves map tiles of slime chunks over HTTP, drawn with the CPU, for viewing in a web map.")
+		fmt.Fprintln(os.Stderr, "Tiles are at /{seed}/{z}/{x}/{y}.png, and search results at /{seed}/overlay/{z}/{x}/{y}.png.")
+		fmt.Fprintf(os.Stderr, "Zoom levels go from %d to %d, where each pixel is one chunk at zoom %d, and zoomed out pixels show the density of slime chunks.\n", tiles.MinZoom, tiles.MaxZoom, tiles.ChunkZoom)
+		fmt.Fprintln(os.Stderr, "A simple viewer is served at /, which takes the seed as a query parameter, such as /?seed=123.")
+		fmt.Fprintln(os.Stderr)
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 0 {
+		flags.Usage()
+		os.Exit(1)
+	}
+
+	edition := parseEditionArg(*editionName)
+	var seed int64
+	if *seedArg != "" {
+		seed = parseSeedArg(*seedArg)
+	} else if *thresholdSpec != "" && edition == slimy.JavaEdition {
+		fmt.Fprintln(os.Stderr, "-results requires -seed for Java Edition")
+		os.Exit(2)
+	}
+
+	server := tiles.NewServer(edition, *workerCount, *cacheSize)
+	server.DefaultSeed = seed
+	if *thresholdSpec != "" {
+		threshold := parseThresholdArg(*thresholdSpec)
+		if *searchRange <= 0 {
+			fmt.Fprintln(os.Stderr, "Range must be positive")
+			os.Exit(2)
+		}
+		x0, z0, x1, z1 := searchArea(parsePosArg(*pos), int64(*searchRange))
+		var err error
+		maskImg := util.GenDonut(1, 8)
+		if *mask != "" {
+			maskImg, err = loadMask(*mask)
+			if err != nil {
+				log.Fatal(err)
+			}
+		}
+		searcher, err := cpu.NewSearcher(*workerCount, maskImg, edition, weightedMask)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+
+		// Stop the search on interrupt, and serve the results found so far
+		ctx, cancel := interruptContext()
+		opts := slimy.Options{Progress: progressPrinter("chunks"), Limit: resultLimit}
+		results, err := searcher.SearchContext(ctx, x0, z0, x1, z1, threshold, seed, opts)
+		interrupted := ctx.Err() != nil
+		cancel()
+		searcher.Destroy()
+		fmt.Fprintln(os.Stderr)
+		if interrupted {
+			fmt.Fprintf(os.Stderr, "Search interrupted, overlaying %d results found so far\n", len(results))
+		} else if err != nil {
+			fmt.Fprintln(os.Stderr, "Search failed:", err)
+			os.Exit(2)
+		} else {
+			fmt.Fprintf(os.Stderr, "Found %d results\n", len(results))
+		}
+		server.SetOverlay(seed, tiles.NewOverlay(results, maskImg, weightedMask))
+	}
+
+	url := "http://" + *listen + "/"
+	if *seedArg != "" {
+		url += "?seed=" + strconv.FormatInt(seed, 10)
+	}
+	fmt.Fprintln(os.Stderr, "Serving tiles at", url)
+	log.Fatal(http.ListenAndServe(*listen, server))
+}
@@ -0,0 +1,167 @@
+package cpu
+
+import (
+	"context"
+	"runtime"
+	"sync"
+
+	"github.com/vktec/slimy"
+)
+
+// Slimes spawn in columns whose centres are within this many blocks of the player, horizontally
+const (
+	SpawnRingInner = 24
+	SpawnRingOuter = 128
+)
+
+const (
+	afkRadius = (SpawnRingOuter + 15) / 16 // Chunks either side of the player's chunk that can be in the spawn ring
+	afkSize   = 2*afkRadius + 1
+)
+
+// How many columns of each chunk around the player are in the spawn ring, for every block the player can stand on within their chunk
+type afkTable struct {
+	// cols[oz*16+ox][dz*afkSize+dx] is for a player at block ox, oz of their chunk, and the chunk dx-afkRadius, dz-afkRadius from theirs
+	cols [256][afkSize * afkSize]uint16
+
+	// Chunks with any columns in the ring from some block of the player's chunk, and chunks entirely in the ring from every block
+	upper, lower Mask
+}
+
+var (
+	afkTableOnce sync.Once
+	afkTab       *afkTable
+)
+
+func getAFKTable() *afkTable {
+	afkTableOnce.Do(func() {
+		t := new(afkTable)
+		t.upper = Mask{afkSize, afkSize, make([]bool, afkSize*afkSize), nil}
+		t.lower = Mask{afkSize, afkSize, make([]bool, afkSize*afkSize), nil}
+		for i := range t.lower.cells {
+			t.lower.cells[i] = true
+		}
+
+		for o := range t.cols {
+			ox, oz := int32(o%16), int32(o/16)
+			for c := range t.cols[o] {
+				dx, dz := int32(c%afkSize)-afkRadius, int32(c/afkSize)-afkRadius
+				cols := uint16(0)
+				for bz := int32(0); bz < 16; bz++ {
+					for bx := int32(0); bx < 16; bx++ {
+						// Both the player and the column are at block centres, so the halves cancel out
+						x, z := 16*dx+bx-ox, 16*dz+bz-oz
+						d2 := x*x + z*z
+						if SpawnRingInner*SpawnRingInner <= d2 && d2 <= SpawnRingOuter*SpawnRingOuter {
+							cols++
+						}
+					}
+				}
+				t.cols[o][c] = cols
+				t.upper.cells[c] = t.upper.cells[c] || cols > 0
+				t.lower.cells[c] = t.lower.cells[c] && cols == 256
+			}
+		}
+		afkTab = t
+	})
+	return afkTab
+}
+
+// Searches for the best places for a player to stand while waiting for slimes to spawn.
+// Every block of the chunks from x0, z0 up to but not including x1, z1 is checked, and results are in block coordinates.
+// Each slime chunk scores the fraction of its columns that are within the spawn ring, so the score is the number of slime chunks the player effectively has.
+//
+// A chunk-level search first finds the chunks where the threshold could be met from some block, by counting every chunk that could be partly in the ring.
+// Only the blocks in those chunks are scored individually.
+// Options.Stream is not supported.
+//
+// If ctx is cancelled during the chunk-level search, the blocks in the chunks it found so far are still scored,
+// and returned along with the context's error.
+func (w World) SearchAFK(ctx context.Context, workerCount int, x0, z0, x1, z1 int32, threshold slimy.Threshold, opts slimy.Options) ([]slimy.BlockResult, error) {
+	if workerCount <= 0 {
+		workerCount = runtime.GOMAXPROCS(0)
+	}
+	table := getAFKTable()
+
+	// The score is at most the number of slime chunks partly in the ring, and at least the number entirely in it
+	min, max := threshold.Bounds()
+	mask, coarse := table.upper, slimy.AtLeast(min)
+	if min == 0 {
+		mask, coarse = table.lower, slimy.AtMost(max)
+	}
+	chunks, err := w.SearchContext(ctx, workerCount, x0, z0, x1, z1, coarse, mask, slimy.Options{Progress: opts.Progress})
+	if err != nil && err != ctx.Err() {
+		return nil, err
+	}
+	// If the chunk-level search was interrupted, score what it found anyway, so that there are results to show
+	done := ctx.Done()
+	if err != nil {
+		done = nil
+	}
+	return w.scoreAFKChunks(done, workerCount, table, chunks, threshold, opts.Limit), ctx.Err()
+}
+
+// Scores every block in the given chunks, unless done is closed, returning the best limit blocks that match the threshold
+func (w World) scoreAFKChunks(done <-chan struct{}, workerCount int, table *afkTable, chunks []slimy.Result, threshold slimy.Threshold, limit int) []slimy.BlockResult {
+	chunkCh := make(chan slimy.Result, workerCount)
+	resultCh := make(chan []slimy.BlockResult, workerCount)
+	wgroup := new(sync.WaitGroup)
+	wgroup.Add(workerCount)
+	for i := 0; i < workerCount; i++ {
+		go func() {
+			for chunk := range chunkCh {
+				select {
+				case <-done:
+				default:
+					resultCh <- w.scoreAFKChunk(table, chunk.X, chunk.Z, threshold)
+				}
+			}
+			wgroup.Done()
+		}()
+	}
+	go func() {
+	chunks:
+		for _, chunk := range chunks {
+			select {
+			case chunkCh <- chunk:
+			case <-done:
+				break chunks
+			}
+		}
+		close(chunkCh)
+		wgroup.Wait()
+		close(resultCh)
+	}()
+
+	var results []slimy.BlockResult
+	for found := range resultCh {
+		results = append(results, found...)
+	}
+	slimy.SortBlockResults(results, threshold.Order())
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
+
+// Scores every block in a chunk, returning those that match the threshold
+func (w World) scoreAFKChunk(table *afkTable, cx, cz int32, threshold slimy.Threshold) (results []slimy.BlockResult) {
+	var slime []int
+	for c := int32(0); c < afkSize*afkSize; c++ {
+		if table.upper.cells[c] && w.CalcChunk(cx+c%afkSize-afkRadius, cz+c/afkSize-afkRadius) {
+			slime = append(slime, int(c))
+		}
+	}
+
+	for o := range table.cols {
+		cols := 0
+		for _, c := range slime {
+			cols += int(table.cols[o][c])
+		}
+		score := float64(cols) / 256
+		if threshold.CheckScore(score) {
+			results = append(results, slimy.BlockResult{X: 16*cx + int32(o%16), Z: 16*cz + int32(o/16), Score: score})
+		}
+	}
+	return results
+}
@@ -0,0 +1,113 @@
+package cpu
+
+import (
+	"context"
+	"testing"
+
+	"github.com/vktec/slimy"
+)
+
+// Scores a player position by checking every column around it
+func bruteForceAFKScore(world World, px, pz int32) float64 {
+	cols := 0
+	for x := px - SpawnRingOuter; x <= px+SpawnRingOuter; x++ {
+		for z := pz - SpawnRingOuter; z <= pz+SpawnRingOuter; z++ {
+			d2 := (x-px)*(x-px) + (z-pz)*(z-pz)
+			if SpawnRingInner*SpawnRingInner <= d2 && d2 <= SpawnRingOuter*SpawnRingOuter && world.CalcChunk(x>>4, z>>4) {
+				cols++
+			}
+		}
+	}
+	return float64(cols) / 256
+}
+
+func TestSearchAFK(t *testing.T) {
+	world := JavaWorld(1)
+	// Score a few chunks' worth of blocks, with some negative coordinates
+	scores := make(map[[2]int32]float64)
+	for pz := int32(-16); pz < 16; pz++ {
+		for px := int32(32); px < 48; px++ {
+			scores[[2]int32{px, pz}] = bruteForceAFKScore(world, px, pz)
+		}
+	}
+
+	for _, threshold := range []slimy.Threshold{slimy.AtLeast(20), slimy.AtMost(19), slimy.Between(19, 20)} {
+		var expected []slimy.BlockResult
+		for pos, score := range scores {
+			if threshold.CheckScore(score) {
+				expected = append(expected, slimy.BlockResult{X: pos[0], Z: pos[1], Score: score})
+			}
+		}
+		slimy.SortBlockResults(expected, threshold.Order())
+		if len(expected) == 0 {
+			t.Fatalf("No results to test with for %v", threshold)
+		}
+
+		results, err := world.SearchAFK(context.Background(), 2, 2, -1, 3, 1, threshold, slimy.Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(results) != len(expected) {
+			t.Fatalf("%v: expected %d results, got %d", threshold, len(expected), len(results))
+		}
+		for i := range results {
+			if results[i] != expected[i] {
+				t.Fatalf("%v: incorrect result at index %d: expected %v, got %v", threshold, i, expected[i], results[i])
+			}
+		}
+	}
+}
+
+func TestSearchAFKCancel(t *testing.T) {
+	world := JavaWorld(1)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// Cancel once the chunk-level search has made some progress
+	opts := slimy.Options{Limit: 20, Progress: func(p slimy.Progress) {
+		if p.Scanned > 0 {
+			cancel()
+		}
+	}}
+	results, err := world.SearchAFK(ctx, 1, -10000, -10000, 10000, 10000, slimy.AtLeast(40), opts)
+	if err != context.Canceled {
+		t.Fatalf("Expected cancellation, got %v", err)
+	}
+	// How far the search got depends on timing, but whatever it found must be right
+	for _, r := range results {
+		if score := bruteForceAFKScore(world, r.X, r.Z); score != r.Score || score < 40 {
+			t.Errorf("Incorrect result at %d, %d: expected score %v, got %v", r.X, r.Z, score, r.Score)
+		}
+	}
+}
+
+func TestScoreAFKChunks(t *testing.T) {
+	world := JavaWorld(1)
+	table := getAFKTable()
+	threshold := slimy.AtLeast(20)
+	chunks := []slimy.Result{{X: 2, Z: -1}, {X: 2, Z: 0}, {X: -7, Z: 3}}
+	var expected []slimy.BlockResult
+	for _, c := range chunks {
+		expected = append(expected, world.scoreAFKChunk(table, c.X, c.Z, threshold)...)
+	}
+	slimy.SortBlockResults(expected, threshold.Order())
+	if len(expected) == 0 {
+		t.Fatal("No results to test with")
+	}
+
+	// Without a done channel, as after an interrupted chunk-level search, every chunk is scored
+	results := world.scoreAFKChunks(nil, 2, table, chunks, threshold, 0)
+	if len(results) != len(expected) {
+		t.Fatalf("Expected %d results, got %d", len(expected), len(results))
+	}
+	for i := range results {
+		if results[i] != expected[i] {
+			t.Fatalf("Incorrect result at index %d: expected %v, got %v", i, expected[i], results[i])
+		}
+	}
+
+	done := make(chan struct{})
+	close(done)
+	if results := world.scoreAFKChunks(done, 2, table, chunks, threshold, 0); len(results) != 0 {
+		t.Errorf("Expected no results once done, got %d", len(results))
+	}
+}
@@ -0,0 +1,253 @@
+package cpu
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/vktec/slimy"
+)
+
+// How often SearchCheckpoint saves its progress
+const CheckpointInterval = time.Minute
+
+// A record of which parts of a search are finished, and what they found.
+// A checkpoint can be saved to a file and used to resume the search later, skipping the finished parts.
+type Checkpoint struct {
+	// The search this checkpoint is for
+	Seed           int64
+	Edition        slimy.Edition
+	X0, Z0, X1, Z1 int32
+	Threshold      slimy.Threshold
+	Mask           string // A hash of the mask
+	Tiles          int    // The number of sections the area is split into
+
+	Done    []byte // A bit for each finished tile, in the order they are searched
+	Results []slimy.Result
+	Limit   int // If positive, only the best Limit results were kept
+}
+
+// Creates an empty checkpoint for a search of the world, with the same arguments as Search
+func NewCheckpoint(w World, x0, z0, x1, z1 int32, threshold slimy.Threshold, mask Mask) *Checkpoint {
+	if x0 > x1 {
+		x0, x1 = x1, x0
+	}
+	if z0 > z1 {
+		z0, z1 = z1, z0
+	}
+	cm := mask.compile()
+	nx, nz := ceilDiv(x1-x0, cm.stepX()), ceilDiv(z1-z0, cm.stepZ())
+	tiles := int(nx) * int(nz)
+	return &Checkpoint{
+		w.Seed, w.Edition,
+		x0, z0, x1, z1,
+		threshold, maskHash(mask), tiles,
+		make([]byte, (tiles+7)/8), nil, 0,
+	}
+}
+
+// Loads a checkpoint saved by Save
+func LoadCheckpoint(name string) (*Checkpoint, error) {
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	cp := new(Checkpoint)
+	if err := json.Unmarshal(data, cp); err != nil {
+		return nil, fmt.Errorf("Invalid checkpoint %s: %w", name, err)
+	}
+	if len(cp.Done) != (cp.Tiles+7)/8 {
+		return nil, fmt.Errorf("Invalid checkpoint %s: wrong number of tiles", name)
+	}
+	return cp, nil
+}
+
+// Writes the checkpoint to a file.
+// The file is replaced atomically, so a crash while saving leaves the previous checkpoint intact.
+func (cp *Checkpoint) Save(name string) error {
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+
+	f, err := ioutil.TempFile(filepath.Dir(name), filepath.Base(name)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(data); err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), name)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+// Reports why the checkpoint can't be used to resume the search described by other, or nil if it can
+func (cp *Checkpoint) Check(other *Checkpoint) error {
+	switch {
+	case cp.Seed != other.Seed:
+		return fmt.Errorf("Checkpoint is for seed %d, not %d", cp.Seed, other.Seed)
+	case cp.Edition != other.Edition:
+		return fmt.Errorf("Checkpoint is for %s, not %s", cp.Edition, other.Edition)
+	case cp.X0 != other.X0 || cp.Z0 != other.Z0 || cp.X1 != other.X1 || cp.Z1 != other.Z1:
+		return fmt.Errorf("Checkpoint is for the area (%d, %d) to (%d, %d), not (%d, %d) to (%d, %d)",
+			cp.X0, cp.Z0, cp.X1, cp.Z1, other.X0, other.Z0, other.X1, other.Z1)
+	case cp.Threshold != other.Threshold:
+		return fmt.Errorf("Checkpoint is for threshold %s, not %s", cp.Threshold, other.Threshold)
+	case cp.Mask != other.Mask || cp.Tiles != other.Tiles:
+		return fmt.Errorf("Checkpoint is for a different mask")
+	}
+	return nil
+}
+
+// Returns the number of finished tiles
+func (cp *Checkpoint) TilesDone() (n int) {
+	for i := 0; i < cp.Tiles; i++ {
+		if cp.tileDone(i) {
+			n++
+		}
+	}
+	return n
+}
+
+func (cp *Checkpoint) tileDone(i int) bool {
+	return cp.Done[i/8]>>(i%8)&1 != 0
+}
+
+// Records a finished tile and its results.
+// If limit is positive, only the best limit results are kept.
+func (cp *Checkpoint) finishTile(i int, results []slimy.Result, limit int) {
+	cp.Done[i/8] |= 1 << (i % 8)
+	cp.Results = append(cp.Results, results...)
+	// Trimming is only worth it once there are plenty of extra results
+	if limit > 0 && len(cp.Results) > 2*limit+1024 {
+		cp.trim(limit)
+	}
+}
+
+// Sorts the results, keeping only the best limit of them if limit is positive
+func (cp *Checkpoint) trim(limit int) {
+	slimy.SortResults(cp.Results, cp.Threshold.Order())
+	if limit > 0 && len(cp.Results) > limit {
+		cp.Results = cp.Results[:limit]
+	}
+}
+
+// Hashes everything about a mask that affects search results
+func maskHash(m Mask) string {
+	h := sha256.New()
+	binary.Write(h, binary.LittleEndian, [2]int32{m.w, m.h})
+	for _, cell := range m.cells {
+		b := byte(0)
+		if cell {
+			b = 1
+		}
+		h.Write([]byte{b})
+	}
+	h.Write(m.weights)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Tracks which tiles of a checkpointed search are finished.
+// Tiles are numbered in the order sendSections sends them.
+type tileTracker struct {
+	done   []byte // Tiles finished before the search started, copied so the checkpoint can be updated while sections are sent
+	nz     int32  // Tiles in each column
+	stepX  int32
+	stepZ  int32
+	x0, z0 int32
+}
+
+// Returns the index of the tile with mask centres starting at x, z
+func (t *tileTracker) index(x, z int32) int {
+	return int((x-t.x0)/t.stepX*t.nz + (z-t.z0)/t.stepZ)
+}
+
+func (t *tileTracker) skip(x, z int32) bool {
+	i := t.index(x, z)
+	return t.done[i/8]>>(i%8)&1 != 0
+}
+
+// Like SearchContext, but records the progress of the search in cp, which must have been created by NewCheckpoint for the same world, area, threshold and mask.
+// Tiles already finished in cp are skipped, and their results are merged with the new ones.
+//
+// save is called with the checkpoint every CheckpointInterval, and once more when the search ends, even if it was cancelled.
+// If save returns an error, the search stops and returns it.
+// Options.Stream is not supported.
+func (w World) SearchCheckpoint(ctx context.Context, workerCount int, cp *Checkpoint, mask Mask, save func(*Checkpoint) error, opts slimy.Options) ([]slimy.Result, error) {
+	if err := cp.Check(NewCheckpoint(w, cp.X0, cp.Z0, cp.X1, cp.Z1, cp.Threshold, mask)); err != nil {
+		return nil, err
+	}
+	if err := checkMaskBounds(mask.Bounds()); err != nil {
+		return nil, err
+	}
+	// Results beyond the checkpoint's limit were thrown away, so a larger limit can't be met
+	if cp.Limit > 0 && (opts.Limit <= 0 || opts.Limit > cp.Limit) && cp.TilesDone() > 0 {
+		return nil, fmt.Errorf("Checkpoint only kept the best %d results", cp.Limit)
+	}
+	cp.Limit = opts.Limit
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	if workerCount <= 0 {
+		workerCount = runtime.GOMAXPROCS(0)
+	}
+	cm := mask.compile()
+	area := int64(cp.X1-cp.X0) * int64(cp.Z1-cp.Z0)
+	progress := slimy.NewProgressReporter(opts.Progress, area)
+	sectionCh := make(chan *Section, 8)
+	resultCh := make(chan worldResults, 8)
+	wgroup := new(sync.WaitGroup)
+	tiles := &tileTracker{append([]byte(nil), cp.Done...), ceilDiv(cp.Z1-cp.Z0, cm.stepZ()), cm.stepX(), cm.stepZ(), cp.X0, cp.Z0}
+	sctx := searchContext{[]World{w}, cp.Threshold, cm, cp.X1, cp.Z1, 0, ctx.Done(), progress, wgroup, sectionCh, resultCh, tiles}
+	// The workers must be counted before sendSections can wait for them
+	wgroup.Add(workerCount)
+	go sctx.sendSections(cp.X0, cp.Z0)
+	for i := 0; i < workerCount; i++ {
+		go sctx.search()
+	}
+
+	var saveErr error
+	lastSave := time.Now()
+	for tileResults := range resultCh {
+		cp.finishTile(tileResults.tile, tileResults.results, opts.Limit)
+		if saveErr == nil && time.Since(lastSave) >= CheckpointInterval {
+			cp.trim(opts.Limit)
+			if saveErr = save(cp); saveErr != nil {
+				cancel()
+			}
+			lastSave = time.Now()
+		}
+	}
+	progress.Finish()
+
+	cp.trim(opts.Limit)
+	if saveErr != nil {
+		return cp.Results, saveErr
+	}
+	if err := save(cp); err != nil {
+		return cp.Results, err
+	}
+	return cp.Results, ctx.Err()
+}
+
+func ceilDiv(a, b int32) int32 {
+	return (a + b - 1) / b
+}
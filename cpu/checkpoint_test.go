@@ -0,0 +1,132 @@
+package cpu
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/vktec/slimy"
+)
+
+func TestSearchCheckpoint(t *testing.T) {
+	world := JavaWorld(5)
+	mask := donut(1, 4)
+	threshold := slimy.AtLeast(7)
+	const x0, z0, x1, z1 = -150, -90, 170, 200
+	expected, _ := world.Search(3, x0, z0, x1, z1, threshold, mask)
+
+	saves := 0
+	save := func(*Checkpoint) error { saves++; return nil }
+	cp := NewCheckpoint(world, x0, z0, x1, z1, threshold, mask)
+	results, err := world.SearchCheckpoint(context.Background(), 3, cp, mask, save, slimy.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkResults(t, results, expected)
+	if saves != 1 {
+		t.Errorf("Expected 1 save, got %d", saves)
+	}
+	if cp.TilesDone() != cp.Tiles {
+		t.Errorf("Only %d of %d tiles done", cp.TilesDone(), cp.Tiles)
+	}
+
+	// Forget every third tile, along with its results
+	cm := mask.compile()
+	tiles := &tileTracker{nil, ceilDiv(z1-z0, cm.stepZ()), cm.stepX(), cm.stepZ(), x0, z0}
+	for i := 0; i < cp.Tiles; i += 3 {
+		cp.Done[i/8] &^= 1 << (i % 8)
+	}
+	var kept []slimy.Result
+	for _, r := range cp.Results {
+		if cp.tileDone(tiles.index(r.X, r.Z)) {
+			kept = append(kept, r)
+		}
+	}
+	cp.Results = kept
+
+	results, err = world.SearchCheckpoint(context.Background(), 3, cp, mask, save, slimy.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkResults(t, results, expected)
+}
+
+func TestSearchCheckpointResume(t *testing.T) {
+	world := JavaWorld(6)
+	mask := donut(1, 3)
+	threshold := slimy.AtLeast(5)
+	const x0, z0, x1, z1 = -100, -100, 100, 100
+	opts := slimy.Options{Limit: 20}
+	expected, _ := world.SearchContext(context.Background(), 3, x0, z0, x1, z1, threshold, mask, opts)
+
+	dir, err := ioutil.TempDir("", "slimy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "checkpoint.json")
+	save := func(cp *Checkpoint) error { return cp.Save(name) }
+
+	// Cancel straight away, so little or nothing is searched
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	cp := NewCheckpoint(world, x0, z0, x1, z1, threshold, mask)
+	if _, err := world.SearchCheckpoint(ctx, 3, cp, mask, save, opts); err != context.Canceled {
+		t.Fatalf("Expected cancellation, got %v", err)
+	}
+
+	cp, err = LoadCheckpoint(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := world.SearchCheckpoint(context.Background(), 3, cp, mask, save, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkResults(t, results, expected)
+
+	// The saved checkpoint has every result
+	cp, err = LoadCheckpoint(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkResults(t, cp.Results, expected)
+	if matches, _ := filepath.Glob(filepath.Join(dir, "*.tmp")); len(matches) > 0 {
+		t.Errorf("Temporary files left behind: %v", matches)
+	}
+}
+
+func TestCheckpointMismatch(t *testing.T) {
+	mask := donut(1, 3)
+	cp := NewCheckpoint(JavaWorld(1), 0, 0, 100, 100, slimy.AtLeast(5), mask)
+	others := []*Checkpoint{
+		NewCheckpoint(JavaWorld(2), 0, 0, 100, 100, slimy.AtLeast(5), mask),
+		NewCheckpoint(BedrockWorld(), 0, 0, 100, 100, slimy.AtLeast(5), mask),
+		NewCheckpoint(JavaWorld(1), 0, 0, 100, 101, slimy.AtLeast(5), mask),
+		NewCheckpoint(JavaWorld(1), 0, 0, 100, 100, slimy.AtLeast(6), mask),
+		NewCheckpoint(JavaWorld(1), 0, 0, 100, 100, slimy.AtLeast(5), donut(2, 3)),
+		NewCheckpoint(JavaWorld(1), 0, 0, 100, 100, slimy.AtLeast(5), weightedMask(3)),
+	}
+	for i, other := range others {
+		if cp.Check(other) == nil {
+			t.Errorf("Checkpoint %d: expected mismatch", i)
+		}
+	}
+	if err := cp.Check(NewCheckpoint(JavaWorld(1), 100, 100, 0, 0, slimy.AtLeast(5), donut(1, 3))); err != nil {
+		t.Error(err)
+	}
+
+	cp.Limit = 10
+	cp.Done[0] = 1
+	for _, limit := range []int{0, 11} {
+		if _, err := JavaWorld(1).SearchCheckpoint(context.Background(), 1, cp, mask, func(*Checkpoint) error { return nil }, slimy.Options{Limit: limit}); err == nil {
+			t.Errorf("Expected resuming with limit %d to fail", limit)
+		}
+	}
+
+	if _, err := JavaWorld(2).SearchCheckpoint(context.Background(), 1, cp, mask, func(*Checkpoint) error { return nil }, slimy.Options{}); err == nil {
+		t.Error("Expected searching the wrong seed to fail")
+	}
+}
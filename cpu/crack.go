@@ -0,0 +1,262 @@
+package cpu
+
+import (
+	"context"
+	"errors"
+	"math"
+	"runtime"
+	"sort"
+	"sync"
+
+	"github.com/vktec/slimy"
+)
+
+// A chunk whose slime status is known
+type Observation struct {
+	X, Z  int32
+	Slime bool
+}
+
+const (
+	seedBits = 48
+	seedMask = 1<<seedBits - 1
+	// Bit 17 of the LCG state only depends on the low 18 bits of the seed
+	lowBits = 18
+	lowMask = 1<<lowBits - 1
+	// XORed with the seed before it reaches the LCG
+	chunkXor = 987234911 ^ magic
+	// The smallest value of Next(31) that NextInt(10) rejects
+	rejectBound = math.MaxInt32 - math.MaxInt32%10
+)
+
+// The multiplicative inverse of magic, modulo 2^48
+var magicInv = func() uint64 {
+	inv := uint64(magic) // Correct to 3 bits, since magic is odd
+	for i := 0; i < 5; i++ {
+		inv *= 2 - magic*inv
+	}
+	return inv & seedMask
+}()
+
+// An observation with its seed-independent term precomputed
+type crackObservation struct {
+	term  int64
+	slime bool
+}
+
+// Returns the LCG state after the first call to Next for the given world seed and chunk term
+func firstState(seed, term int64) uint64 {
+	return (uint64((seed+term)^chunkXor)*magic + 0xB) & seedMask
+}
+
+// Reports whether the world seed is consistent with the observation.
+// This is exact, since the rare first draws that NextInt rejects fall back to the full calculation,
+// though the earlier stages of Crack are not (see Crack).
+func (o crackObservation) matches(seed int64) bool {
+	v := firstState(seed, o.term) >> 17
+	if v >= rejectBound {
+		return o.matchesSlow(seed)
+	}
+	return (v%10 == 0) == o.slime
+}
+
+func (o crackObservation) matchesSlow(seed int64) bool {
+	return JavaWorld(seed).calcChunkTerm(o.term) == o.slime
+}
+
+// Finds every value of the lower 48 bits of the world seed that is consistent with the observations.
+// Only Java Edition worlds can be cracked, since Bedrock Edition slime chunks don't depend on the seed.
+// Only the lower 48 bits of the world seed affect slime chunks, so any seed with the same lower 48 bits is equally valid.
+//
+// Rather than trying all 2^48 seeds, the search works in two stages.
+// A slime chunk's first draw from the LCG must be even, and that parity bit only depends on the low 18 bits of the seed, so those are found first.
+// For each surviving set of low bits, the LCG is then run backwards from every first draw that would make the first observed slime chunk a slime chunk, giving the remaining 30 bits directly.
+// Both stages assume that each observed slime chunk's first draw is accepted by NextInt,
+// so a seed is missed if any of them is rejected, which has a chance of about 1 in 270 million per slime chunk.
+//
+// The second stage checks about 200 million seeds for each set of low bits that survives the first stage.
+// Each slime chunk rules out roughly half of the sets of low bits, until only the right one is left,
+// so with 10 slime chunks cracking takes around half an hour of CPU time, and with 30 or more just a few seconds.
+//
+// If progress is not nil, it is called periodically with the number of candidate seeds checked.
+func Crack(ctx context.Context, workerCount int, observations []Observation, progress func(slimy.Progress)) ([]int64, error) {
+	var slimeObs, otherObs []crackObservation
+	for _, o := range observations {
+		co := crackObservation{chunkTermX(o.X) + chunkTermZ(o.Z), o.Slime}
+		if o.Slime {
+			slimeObs = append(slimeObs, co)
+		} else {
+			otherObs = append(otherObs, co)
+		}
+	}
+	if len(slimeObs) == 0 {
+		return nil, errors.New("At least one slime chunk must be observed")
+	}
+	// Check the most selective observations first
+	obs := append(slimeObs[1:len(slimeObs):len(slimeObs)], otherObs...)
+
+	if workerCount <= 0 {
+		workerCount = runtime.GOMAXPROCS(0)
+	}
+
+	lows := crackLowBits(slimeObs)
+	// Every multiple of 10 below rejectBound is a possible first draw
+	const draws = rejectBound / 10
+	reporter := slimy.NewProgressReporter(progress, int64(len(lows))*draws)
+
+	type job struct{ low, m0, m1 int64 }
+	jobCh := make(chan job, workerCount)
+	resultCh := make(chan []int64, workerCount)
+	wgroup := new(sync.WaitGroup)
+	wgroup.Add(workerCount)
+	for i := 0; i < workerCount; i++ {
+		go func() {
+			for j := range jobCh {
+				if ctx.Err() != nil {
+					continue
+				}
+				resultCh <- crackHighBits(slimeObs[0], obs, j.low, j.m0, j.m1)
+				reporter.Add(j.m1 - j.m0)
+			}
+			wgroup.Done()
+		}()
+	}
+
+	go func() {
+		// Split the draws for each set of low bits into chunks, so cancellation is quick
+		const chunk = 1 << 22
+	jobs:
+		for _, low := range lows {
+			for m := int64(0); m < draws; m += chunk {
+				m1 := m + chunk
+				if m1 > draws {
+					m1 = draws
+				}
+				select {
+				case jobCh <- job{low, m, m1}:
+				case <-ctx.Done():
+					break jobs
+				}
+			}
+		}
+		close(jobCh)
+		wgroup.Wait()
+		close(resultCh)
+	}()
+
+	var seeds []int64
+	for found := range resultCh {
+		seeds = append(seeds, found...)
+	}
+	sort.Slice(seeds, func(i, j int) bool { return seeds[i] < seeds[j] })
+	reporter.Finish()
+	return seeds, ctx.Err()
+}
+
+// Returns every value of the low 18 bits of the seed that gives an even first draw for each slime chunk
+func crackLowBits(slimeObs []crackObservation) (lows []int64) {
+	for low := int64(0); low <= lowMask; low++ {
+		ok := true
+		for _, o := range slimeObs {
+			if firstState(low, o.term)>>17&1 != 0 {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			lows = append(lows, low)
+		}
+	}
+	return lows
+}
+
+// Finds the seeds with the given low bits whose first draw for first is 10*m, for m from m0 up to m1, and that match every other observation
+func crackHighBits(first crackObservation, obs []crackObservation, low, m0, m1 int64) (seeds []int64) {
+	// The low 17 bits of the state are fixed by the low bits of the seed
+	stateLow := firstState(low, first.term) & (1<<17 - 1)
+	for m := m0; m < m1; m++ {
+		state := uint64(10*m)<<17 | stateLow
+		internal := ((state - 0xB) * magicInv) & seedMask
+		seed := (int64(internal) ^ chunkXor - first.term) & seedMask
+
+		ok := true
+		for _, o := range obs {
+			if !o.matches(seed) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			seeds = append(seeds, seed)
+		}
+	}
+	return seeds
+}
+
+// Estimates how many more observations are needed to narrow the given number of candidate seeds down to one.
+// Returns the number of slime chunks that would be needed, and the number of chunks picked at random, of which about one in ten will be slime chunks.
+func ObservationsNeeded(candidates int) (slimeChunks, randomChunks int) {
+	if candidates <= 1 {
+		return 0, 0
+	}
+	// Each slime chunk rules out 9 in 10 of the wrong seeds, and each other chunk rules out 1 in 10.
+	// A random chunk gives the average of the two, weighted by how often each occurs.
+	n := math.Log(float64(candidates))
+	perRandom := 0.1*math.Log(10) + 0.9*math.Log(10.0/9.0)
+	return int(math.Ceil(n / math.Log(10))), int(math.Ceil(n / perRandom))
+}
+
+// The most candidates SplitCandidates checks each chunk against
+const MaxSplitSample = 1000
+
+// Finds the chunk from x0, z0 up to but not including x1, z1 that splits the candidate seeds most evenly between slime and non-slime.
+// Returns the chunk, and the number of candidates for which it is a slime chunk.
+// Observing the chunk this returns narrows the candidates down faster than observing a random chunk.
+//
+// With more than MaxSplitSample candidates, chunks are compared using an evenly spaced sample of them, though the count returned is still exact.
+// If ctx is cancelled, the best chunk found so far is returned along with the context's error.
+// If progress is not nil, it is called periodically with the number of chunks checked.
+func SplitCandidates(ctx context.Context, seeds []int64, x0, z0, x1, z1 int32, progress func(slimy.Progress)) (bestX, bestZ int32, bestSlime int, err error) {
+	sample := seeds
+	if len(seeds) > MaxSplitSample {
+		sample = make([]int64, MaxSplitSample)
+		for i := range sample {
+			sample[i] = seeds[i*len(seeds)/MaxSplitSample]
+		}
+	}
+
+	reporter := slimy.NewProgressReporter(progress, int64(x1-x0)*int64(z1-z0))
+	bestX, bestZ = x0, z0
+	bestScore := -1
+	for z := z0; z < z1; z++ {
+		if ctx.Err() != nil {
+			break
+		}
+		for x := x0; x < x1; x++ {
+			slime := 0
+			for _, seed := range sample {
+				if JavaWorld(seed).CalcChunk(x, z) {
+					slime++
+				}
+			}
+
+			// The fewer candidates the worst outcome leaves, the better
+			score := slime
+			if len(sample)-slime < score {
+				score = len(sample) - slime
+			}
+			if score > bestScore {
+				bestX, bestZ, bestScore = x, z, score
+			}
+		}
+		reporter.Add(int64(x1 - x0))
+	}
+	reporter.Finish()
+
+	for _, seed := range seeds {
+		if JavaWorld(seed).CalcChunk(bestX, bestZ) {
+			bestSlime++
+		}
+	}
+	return bestX, bestZ, bestSlime, ctx.Err()
+}
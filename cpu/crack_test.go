@@ -0,0 +1,144 @@
+package cpu
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+)
+
+func TestMagicInv(t *testing.T) {
+	if magic*magicInv&seedMask != 1 {
+		t.Fatalf("Incorrect inverse: %#x", magicInv)
+	}
+}
+
+// Observes chunks near the origin until 40 slime chunks have been seen, which is enough for cracking to be quick
+func observe(world World) (obs []Observation) {
+	slime := 0
+	for z := int32(-20); slime < 40; z++ {
+		for x := int32(-20); x < 20; x++ {
+			o := Observation{x, z, world.CalcChunk(x, z)}
+			if o.Slime {
+				slime++
+				obs = append(obs, o)
+			} else if (x+z)%5 == 0 {
+				obs = append(obs, o)
+			}
+		}
+	}
+	return obs
+}
+
+// Checks that the world's seed is one of the candidates, and that every candidate matches the observations
+func checkCandidates(t *testing.T, world World, obs []Observation, seeds []int64) {
+	found := false
+	for _, seed := range seeds {
+		found = found || seed == world.Seed&seedMask
+		for _, o := range obs {
+			if JavaWorld(seed).CalcChunk(o.X, o.Z) != o.Slime {
+				t.Errorf("Candidate %d does not match observation %v", seed, o)
+			}
+		}
+	}
+	if !found {
+		t.Errorf("World seed not found in candidates %v", seeds)
+	}
+}
+
+func TestCrackStages(t *testing.T) {
+	world := JavaWorld(-4172144997902289642)
+	seed := world.Seed & seedMask
+	obs := observe(world)
+	var slimeObs, otherObs []crackObservation
+	for _, o := range obs {
+		co := crackObservation{chunkTermX(o.X) + chunkTermZ(o.Z), o.Slime}
+		if o.Slime {
+			slimeObs = append(slimeObs, co)
+		} else {
+			otherObs = append(otherObs, co)
+		}
+	}
+
+	found := false
+	for _, low := range crackLowBits(slimeObs) {
+		found = found || low == seed&lowMask
+	}
+	if !found {
+		t.Fatal("Low bits of the world seed not found")
+	}
+
+	// Only search the draws near the world seed's first draw
+	m := int64(firstState(seed, slimeObs[0].term)>>17) / 10
+	seeds := crackHighBits(slimeObs[0], append(slimeObs[1:], otherObs...), seed&lowMask, m-1000, m+1000)
+	checkCandidates(t, world, obs, seeds)
+}
+
+func TestCrack(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Cracking takes a while")
+	}
+	world := JavaWorld(-4172144997902289642)
+	obs := observe(world)
+	seeds, err := Crack(context.Background(), 0, obs, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkCandidates(t, world, obs, seeds)
+}
+
+func TestCrackNoSlime(t *testing.T) {
+	if _, err := Crack(context.Background(), 0, []Observation{{0, 0, false}}, nil); err == nil {
+		t.Error("Expected an error without any slime chunks")
+	}
+}
+
+func TestSplitCandidates(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	seeds := make([]int64, 8)
+	for i := range seeds {
+		seeds[i] = rng.Int63()
+	}
+	x, z, slime, err := SplitCandidates(context.Background(), seeds, -10, -10, 10, 10, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	count := 0
+	for _, seed := range seeds {
+		if JavaWorld(seed).CalcChunk(x, z) {
+			count++
+		}
+	}
+	if count != slime {
+		t.Errorf("Expected %d candidates with a slime chunk at %d, %d, got %d", count, x, z, slime)
+	}
+	if slime != 4 {
+		t.Errorf("Expected an even split, got %d slime chunks", slime)
+	}
+}
+
+func TestSplitCandidatesSample(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	seeds := make([]int64, 3*MaxSplitSample)
+	for i := range seeds {
+		seeds[i] = rng.Int63()
+	}
+	x, z, slime, err := SplitCandidates(context.Background(), seeds, -4, -4, 4, 4, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	count := 0
+	for _, seed := range seeds {
+		if JavaWorld(seed).CalcChunk(x, z) {
+			count++
+		}
+	}
+	if count != slime {
+		t.Errorf("Expected %d candidates with a slime chunk at %d, %d, got %d", count, x, z, slime)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, _, _, err := SplitCandidates(ctx, seeds, -1000, -1000, 1000, 1000, nil); err != context.Canceled {
+		t.Errorf("Expected cancellation, got %v", err)
+	}
+}
@@ -9,8 +9,20 @@ import (
 	"github.com/vktec/slimy"
 )
 
+// The colours DrawArea uses
+var (
+	DefaultBackgroundColor color.Color = color.RGBA{0, 0, 0, 255}
+	DefaultSlimeChunkColor color.Color = color.RGBA{100, 255, 100, 255}
+)
+
 // Draws slime chunks on an image. The search area comes from the image's Bounds
 func (w World) DrawArea(workerCount int, dst draw.Image) {
+	w.DrawAreaColors(workerCount, dst, DefaultBackgroundColor, DefaultSlimeChunkColor)
+}
+
+// Like DrawArea, but with the given colours for slime chunks and other chunks.
+// Sections are computed concurrently, but dst is only written to from the calling goroutine, so it needn't be safe for concurrent use.
+func (w World) DrawAreaColors(workerCount int, dst draw.Image, background, slimeChunk color.Color) {
 	if workerCount <= 0 {
 		workerCount = runtime.GOMAXPROCS(0)
 	}
@@ -20,37 +32,42 @@ func (w World) DrawArea(workerCount int, dst draw.Image) {
 	z0, z1 := int32(bounds.Min.Y), int32(bounds.Max.Y)
 
 	sectionCh := make(chan *Section, 8)
-	resultCh := make(chan []slimy.Result, 8)
+	resultCh := make(chan worldResults, 8)
+	drawCh := make(chan *Section, 8)
 	wgroup := new(sync.WaitGroup)
-	ctx := searchContext{w, 0, Mask{}, wgroup, sectionCh, resultCh}
-	go ctx.sendSections(x0, z0, x1, z1)
-
+	ctx := searchContext{[]World{w}, slimy.Threshold{}, Mask{1, 1, []bool{false}, nil}.compile(), x1, z1, 0, nil, nil, wgroup, sectionCh, resultCh, nil}
+	// The workers must be counted before sendSections can wait for them
 	wgroup.Add(workerCount)
+	go ctx.sendSections(x0, z0)
 	for i := 0; i < workerCount; i++ {
-		go ctx.draw(dst)
-	}
-
-	for range resultCh {
+		go ctx.draw(drawCh)
 	}
-}
+	go func() {
+		wgroup.Wait()
+		close(drawCh)
+	}()
 
-var (
-	backgroundColor = color.RGBA{0, 0, 0, 255}
-	slimeChunkColor = color.RGBA{100, 255, 100, 255}
-)
-
-func (ctx searchContext) draw(dst draw.Image) {
-	for sec := range ctx.sectionCh {
-		sec.Compute(ctx.world)
-		for z := int32(0); z < SectionSize; z++ {
-			for x := int32(0); x < SectionSize; x++ {
-				color := backgroundColor
+	for sec := range drawCh {
+		// Sections at the edges extend past the image, so only draw the part inside it
+		w, h := min32(sec.Size, x1-sec.X), min32(sec.Size, z1-sec.Z)
+		for z := int32(0); z < h; z++ {
+			for x := int32(0); x < w; x++ {
+				c := background
 				if sec.Get(x, z) {
-					color = slimeChunkColor
+					c = slimeChunk
 				}
-				dst.Set(int(x+sec.X), int(z+sec.Z), color)
+				dst.Set(int(x+sec.X), int(z+sec.Z), c)
 			}
 		}
+		putSection(sec)
+	}
+}
+
+// Computes sections and passes them to drawCh, which returns them to the pool once they are drawn
+func (ctx searchContext) draw(drawCh chan<- *Section) {
+	for sec := range ctx.sectionCh {
+		sec.Compute(ctx.worlds[0])
+		drawCh <- sec
 	}
 	ctx.wgroup.Done()
 }
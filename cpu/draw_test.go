@@ -0,0 +1,51 @@
+package cpu
+
+import (
+	"image"
+	"image/color"
+	"sync/atomic"
+	"testing"
+)
+
+// An image that records how it is drawn to, and fails the test if it is drawn to concurrently or out of bounds
+type checkedImage struct {
+	*image.RGBA
+	t       *testing.T
+	drawing int32
+	sets    map[image.Point]int
+}
+
+func (img *checkedImage) Set(x, y int, c color.Color) {
+	if !atomic.CompareAndSwapInt32(&img.drawing, 0, 1) {
+		img.t.Error("Concurrent Set")
+	}
+	if !(image.Point{x, y}).In(img.Bounds()) {
+		img.t.Errorf("Set out of bounds at %d, %d", x, y)
+	}
+	img.sets[image.Point{x, y}]++
+	img.RGBA.Set(x, y, c)
+	atomic.StoreInt32(&img.drawing, 0)
+}
+
+func TestDrawArea(t *testing.T) {
+	world := JavaWorld(7)
+	// Bounds that aren't a multiple of the section size
+	bounds := image.Rect(-150, -70, 190, 230)
+	img := &checkedImage{image.NewRGBA(bounds), t, 0, make(map[image.Point]int)}
+	world.DrawArea(4, img)
+
+	for z := bounds.Min.Y; z < bounds.Max.Y; z++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			expected := DefaultBackgroundColor
+			if world.CalcChunk(int32(x), int32(z)) {
+				expected = DefaultSlimeChunkColor
+			}
+			if img.At(x, z) != expected {
+				t.Fatalf("Wrong colour at %d, %d", x, z)
+			}
+			if n := img.sets[image.Point{x, z}]; n != 1 {
+				t.Fatalf("Chunk %d, %d drawn %d times", x, z, n)
+			}
+		}
+	}
+}
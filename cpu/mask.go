@@ -1,21 +1,169 @@
 package cpu
 
-import "fmt"
+import (
+	"fmt"
+	"image"
 
+	"github.com/vktec/slimy/util"
+)
+
+// The set of chunks counted around each search position.
+// Results are reported at the centre of the mask's bounds.
 type Mask struct {
-	ORad, IRad int32
+	w, h  int32
+	cells []bool
+
+	// Weights for each cell, from 0 to util.MaxMaskWeight. Nil if every cell in the mask has full weight
+	weights []uint8
+}
+
+// Creates a mask from an image, using the same rules as the GPU searcher
+func NewMask(img image.Image) Mask {
+	dim := img.Bounds().Canon()
+	m := Mask{int32(dim.Dx()), int32(dim.Dy()), make([]bool, dim.Dx()*dim.Dy()), nil}
+	for y := dim.Min.Y; y < dim.Max.Y; y++ {
+		for x := dim.Min.X; x < dim.Max.X; x++ {
+			m.cells[(y-dim.Min.Y)*dim.Dx()+x-dim.Min.X] = util.InMask(img.At(x, y))
+		}
+	}
+	return m
+}
+
+// Creates a mask where each cell is weighted by the brightness of its pixel, using the same rules as the GPU searcher.
+// Cells with zero weight are not part of the mask.
+func NewWeightedMask(img image.Image) Mask {
+	dim := img.Bounds().Canon()
+	m := Mask{int32(dim.Dx()), int32(dim.Dy()), make([]bool, dim.Dx()*dim.Dy()), make([]uint8, dim.Dx()*dim.Dy())}
+	for y := dim.Min.Y; y < dim.Max.Y; y++ {
+		for x := dim.Min.X; x < dim.Max.X; x++ {
+			i := (y-dim.Min.Y)*dim.Dx() + x - dim.Min.X
+			m.weights[i] = util.MaskWeight(img.At(x, y))
+			m.cells[i] = m.weights[i] > 0
+		}
+	}
+	return m
 }
 
 func (m Mask) Bounds() (w, h int32) {
-	w = 2*m.ORad + 1
-	return w, w
+	return m.w, m.h
 }
 
 func (m Mask) Query(x, z int32) bool {
-	x -= m.ORad
-	z -= m.ORad
-	d2 := x*x + z*z
-	return m.IRad*m.IRad < d2 && d2 <= m.ORad*m.ORad
+	return m.cells[z*m.w+x]
+}
+
+// Returns the weight of a cell, from 0 to 1
+func (m Mask) Weight(x, z int32) float64 {
+	if m.weights == nil {
+		if m.Query(x, z) {
+			return 1
+		}
+		return 0
+	}
+	return float64(m.weights[z*m.w+x]) / util.MaxMaskWeight
+}
+
+// A mask prepared for searching
+type compiledMask struct {
+	w, h  int32
+	words int32    // Number of words in each row
+	bits  []uint64 // Row bitmasks, in the same layout as Section rows
+	edges maskEdges
+	size  int32 // Size of sections to search with this mask
+
+	// Whether sliding the mask along a row is cheaper than counting it in full.
+	// Sliding costs a lookup per edge chunk, while counting costs a popcount per word, which is about three times as expensive.
+	incremental bool
+
+	// For weighted masks, a mask of the cells with each bit of their weight set, from least to most significant.
+	// The weighted sum is the sum of each plane's count, shifted by the plane's bit. Planes with no cells are nil.
+	planes []*compiledMask
+}
+
+func (m Mask) compile() *compiledMask {
+	w, h := m.Bounds()
+	query := func(x, z int32) bool {
+		return 0 <= x && x < w && 0 <= z && z < h && m.Query(x, z)
+	}
+
+	cm := compileBits(w, h, query)
+	cm.size = sectionSize(w, h)
+	cm.edges = newMaskEdges(w, h, query)
+	cm.incremental = len(cm.edges.addX)+len(cm.edges.subX) < 3*int(h*cm.words)
+
+	if m.weights != nil {
+		cm.planes = make([]*compiledMask, 8)
+		for b := range cm.planes {
+			empty := true
+			plane := compileBits(w, h, func(x, z int32) bool {
+				set := m.weights[z*w+x]>>b&1 != 0
+				empty = empty && !set
+				return set
+			})
+			if !empty {
+				cm.planes[b] = plane
+			}
+		}
+	}
+	return cm
+}
+
+// Compiles just the row bitmasks of a mask
+func compileBits(w, h int32, query func(x, z int32) bool) *compiledMask {
+	words := (w + 63) / 64
+	cm := &compiledMask{w: w, h: h, words: words, bits: make([]uint64, words*h)}
+	for z := int32(0); z < h; z++ {
+		row := cm.row(z)
+		for x := int32(0); x < w; x++ {
+			if query(x, z) {
+				row[x/64] |= 1 << (x % 64)
+			}
+		}
+	}
+	return cm
+}
+
+// The number of positions at which the mask fits within a section, along each axis
+func (cm *compiledMask) stepX() int32 {
+	return cm.size - cm.w + 1
+}
+func (cm *compiledMask) stepZ() int32 {
+	return cm.size - cm.h + 1
+}
+
+func (cm *compiledMask) row(z int32) []uint64 {
+	return cm.bits[z*cm.words : (z+1)*cm.words]
+}
+
+type maskPoint struct {
+	x, z int32
+}
+
+// The chunks that enter and leave a mask when it moves one step in the positive direction along either axis.
+// Points are relative to the mask's position before the move.
+type maskEdges struct {
+	addX, subX []maskPoint
+	addZ, subZ []maskPoint
+}
+
+func newMaskEdges(w, h int32, query func(x, z int32) bool) (e maskEdges) {
+	// A chunk enters the mask when the cell before it is set and its own cell is not, and leaves in the opposite case
+	for z := int32(0); z <= h; z++ {
+		for x := int32(0); x <= w; x++ {
+			cur := query(x, z)
+			if prev := query(x-1, z); prev && !cur {
+				e.addX = append(e.addX, maskPoint{x, z})
+			} else if !prev && cur {
+				e.subX = append(e.subX, maskPoint{x, z})
+			}
+			if prev := query(x, z-1); prev && !cur {
+				e.addZ = append(e.addZ, maskPoint{x, z})
+			} else if !prev && cur {
+				e.subZ = append(e.subZ, maskPoint{x, z})
+			}
+		}
+	}
+	return e
 }
 
 func (m Mask) Print() {
@@ -0,0 +1,74 @@
+// Go implementation of the 32-bit Mersenne Twister, MT19937
+// Not safe for concurrent use
+package cpu
+
+const (
+	mtN       = 624
+	mtM       = 397
+	mtMatrixA = 0x9908B0DF
+	mtInit    = 1812433253
+)
+
+type MT19937 struct {
+	state [mtN]uint32
+	index int
+}
+
+func NewMT19937(seed uint32) *MT19937 {
+	mt := new(MT19937)
+	mt.SetSeed(seed)
+	return mt
+}
+
+func (mt *MT19937) SetSeed(seed uint32) {
+	mt.state[0] = seed
+	for i := 1; i < mtN; i++ {
+		prev := mt.state[i-1]
+		mt.state[i] = mtInit*(prev^prev>>30) + uint32(i)
+	}
+	mt.index = mtN
+}
+
+// Generates the next block of state
+func (mt *MT19937) twist() {
+	for i := 0; i < mtN; i++ {
+		y := mt.state[i]&0x80000000 | mt.state[(i+1)%mtN]&0x7fffffff
+		mt.state[i] = mt.state[(i+mtM)%mtN] ^ mtTwist(y)
+	}
+	mt.index = 0
+}
+
+func mtTwist(y uint32) uint32 {
+	return y>>1 ^ (y&1)*mtMatrixA
+}
+
+func mtTemper(y uint32) uint32 {
+	y ^= y >> 11
+	y ^= y << 7 & 0x9D2C5680
+	y ^= y << 15 & 0xEFC60000
+	y ^= y >> 18
+	return y
+}
+
+func (mt *MT19937) Next() uint32 {
+	if mt.index >= mtN {
+		mt.twist()
+	}
+	y := mt.state[mt.index]
+	mt.index++
+	return mtTemper(y)
+}
+
+// Returns the first output of an MT19937 with the given seed.
+// This only depends on state words 0, 1 and 397, so it is much faster than seeding a full generator.
+func mtFirst(seed uint32) uint32 {
+	s, s1 := seed, uint32(0)
+	for i := uint32(1); i <= mtM; i++ {
+		s = mtInit*(s^s>>30) + i
+		if i == 1 {
+			s1 = s
+		}
+	}
+	y := seed&0x80000000 | s1&0x7fffffff
+	return mtTemper(s ^ mtTwist(y))
+}
@@ -0,0 +1,48 @@
+package cpu
+
+import "testing"
+
+func checkMT(t *testing.T, path string, seed uint32) {
+	mt := NewMT19937(seed)
+	for i, n := range readInts(path) {
+		if n2 := mt.Next(); uint32(n) != n2 {
+			t.Fatalf("%s: expected %d at index %d, got %d", path, n, i, n2)
+		}
+	}
+}
+
+func TestMT19937(t *testing.T) {
+	checkMT(t, "mt19937_s5489.txt", 5489)
+	checkMT(t, "mt19937_s1010.txt", 1010)
+}
+
+// The C++ standard requires this of std::mt19937
+func TestMT19937Standard(t *testing.T) {
+	mt := NewMT19937(5489)
+	for i := 0; i < 9999; i++ {
+		mt.Next()
+	}
+	if v := mt.Next(); v != 4123659995 {
+		t.Errorf("Expected 4123659995 as the 10000th output, got %d", v)
+	}
+}
+
+func TestMTFirst(t *testing.T) {
+	for _, seed := range []uint32{0, 1, 5489, 0x80000000, 0xffffffff, 123456789} {
+		if v, expected := mtFirst(seed), NewMT19937(seed).Next(); v != expected {
+			t.Errorf("Seed %d: expected %d, got %d", seed, expected, v)
+		}
+	}
+	// First outputs of std::mt19937, including seeds of chunks next to the origin
+	for seed, expected := range map[uint32]uint32{
+		0:          2357136044,
+		1:          1791095845,
+		0x1f1f1f1f: 225390821,
+		0xe0e0e0e1: 2685975470,
+		0xdeadbeef: 956529277,
+	} {
+		if v := mtFirst(seed); v != expected {
+			t.Errorf("Seed %#x: expected %d, got %d", seed, expected, v)
+		}
+	}
+}
@@ -2,14 +2,19 @@
 // Not safe for concurrent use
 package cpu
 
+import "math"
+
 type Random struct {
 	seed int64
+
+	nextNextGaussian     float64
+	haveNextNextGaussian bool
 }
 
 const magic = 0x5DEECE66D
 
 func NewRandom(seed int64) Random {
-	return Random{mixSeed(seed)}
+	return Random{seed: mixSeed(seed)}
 }
 
 func mixSeed(seed int64) int64 {
@@ -18,6 +23,7 @@ func mixSeed(seed int64) int64 {
 
 func (r *Random) SetSeed(seed int64) {
 	r.seed = mixSeed(seed)
+	r.haveNextNextGaussian = false
 }
 
 func (r *Random) Next(bits int) int32 {
@@ -39,3 +45,154 @@ func (r *Random) NextInt(n int32) int32 {
 		}
 	}
 }
+
+// Java's nextInt()
+func (r *Random) NextInt32() int32 {
+	return r.Next(32)
+}
+
+// Returns a number from origin up to but not including bound, like Java 17's nextInt(origin, bound)
+func (r *Random) NextIntRange(origin, bound int32) int32 {
+	if origin >= bound {
+		panic("bound must be greater than origin")
+	}
+
+	v := r.NextInt32()
+	n := bound - origin
+	m := n - 1
+	if n&m == 0 {
+		return v&m + origin
+	} else if n > 0 {
+		u := int32(uint32(v) >> 1)
+		for v = u % n; u+m-v < 0; v = u % n {
+			u = int32(uint32(r.NextInt32()) >> 1)
+		}
+		return v + origin
+	} else {
+		// The range is too large for int32, so just keep trying until the number is in range
+		for v < origin || v >= bound {
+			v = r.NextInt32()
+		}
+		return v
+	}
+}
+
+func (r *Random) NextLong() int64 {
+	return int64(r.Next(32))<<32 + int64(r.Next(32))
+}
+
+// Returns a number from 0 up to but not including bound, like Java 17's nextLong(bound)
+func (r *Random) NextLongBounded(bound int64) int64 {
+	if bound <= 0 {
+		panic("bound must be positive")
+	}
+
+	v := r.NextLong()
+	m := bound - 1
+	if bound&m == 0 {
+		return v & m
+	}
+	u := int64(uint64(v) >> 1)
+	for v = u % bound; u+m-v < 0; v = u % bound {
+		u = int64(uint64(r.NextLong()) >> 1)
+	}
+	return v
+}
+
+// Returns a number from origin up to but not including bound, like Java 17's nextLong(origin, bound)
+func (r *Random) NextLongRange(origin, bound int64) int64 {
+	if origin >= bound {
+		panic("bound must be greater than origin")
+	}
+
+	v := r.NextLong()
+	n := bound - origin
+	m := n - 1
+	if n&m == 0 {
+		return v&m + origin
+	} else if n > 0 {
+		u := int64(uint64(v) >> 1)
+		for v = u % n; u+m-v < 0; v = u % n {
+			u = int64(uint64(r.NextLong()) >> 1)
+		}
+		return v + origin
+	} else {
+		// The range is too large for int64, so just keep trying until the number is in range
+		for v < origin || v >= bound {
+			v = r.NextLong()
+		}
+		return v
+	}
+}
+
+func (r *Random) NextBoolean() bool {
+	return r.Next(1) != 0
+}
+
+func (r *Random) NextFloat() float32 {
+	return float32(r.Next(24)) / (1 << 24)
+}
+
+func (r *Random) NextDouble() float64 {
+	return float64(int64(r.Next(26))<<27+int64(r.Next(27))) * 0x1p-53
+}
+
+// Generates numbers in pairs using the polar method, so every other call uses no randomness
+func (r *Random) NextGaussian() float64 {
+	if r.haveNextNextGaussian {
+		r.haveNextNextGaussian = false
+		return r.nextNextGaussian
+	}
+
+	var v1, v2, s float64
+	for {
+		v1 = 2*r.NextDouble() - 1
+		v2 = 2*r.NextDouble() - 1
+		// Prevent fusing into an FMA instruction, which would round differently to Java
+		s = float64(v1*v1) + float64(v2*v2)
+		if s < 1 && s != 0 {
+			break
+		}
+	}
+	multiplier := math.Sqrt(-2 * strictLog(s) / s)
+	r.nextNextGaussian = v2 * multiplier
+	r.haveNextNextGaussian = true
+	return v1 * multiplier
+}
+
+// Fills buf with random bytes, using one call to NextInt32 for every four bytes
+func (r *Random) NextBytes(buf []byte) {
+	for i := 0; i < len(buf); {
+		v := r.NextInt32()
+		for n := 0; n < 4 && i < len(buf); n++ {
+			buf[i] = byte(v)
+			v >>= 8
+			i++
+		}
+	}
+}
+
+// Returns the multiplier and addend that advance the LCG by n steps at once
+func lcgSkip(n int64) (mul, add int64) {
+	mul, add = 1, 0
+	stepMul, stepAdd := int64(magic), int64(0xB)
+	// The LCG has a period of 2^48, so stepping back is the same as stepping forward by the rest of the period
+	for steps := uint64(n) & ((1 << 48) - 1); steps > 0; steps >>= 1 {
+		if steps&1 != 0 {
+			mul *= stepMul
+			add = add*stepMul + stepAdd
+		}
+		stepAdd *= stepMul + 1
+		stepMul *= stepMul
+	}
+	return mul & ((1 << 48) - 1), add & ((1 << 48) - 1)
+}
+
+// Advances the generator by n calls to Next in O(log n) time.
+// A negative n steps the generator back.
+// Like SetSeed, this discards any saved NextGaussian value.
+func (r *Random) Skip(n int64) {
+	mul, add := lcgSkip(n)
+	r.seed = (r.seed*mul + add) & ((1 << 48) - 1)
+	r.haveNextNextGaussian = false
+}
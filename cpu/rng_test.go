@@ -48,3 +48,153 @@ func TestNextIntNonPow2(t *testing.T) {
 		}
 	}
 }
+
+func readFloats(path string) []float64 {
+	f, err := os.Open(filepath.Join("testdata", path))
+	if err != nil {
+		panic(err)
+	}
+	defer f.Close()
+
+	var ret []float64
+	for {
+		var v float64
+		if _, err := fmt.Fscan(f, &v); err == io.EOF {
+			return ret
+		} else if err != nil {
+			panic(err)
+		}
+		ret = append(ret, v)
+	}
+}
+
+// Checks a generator against a test vector file of integers
+func checkInts(t *testing.T, path string, next func(r *Random) int64) {
+	r := NewRandom(1010)
+	for i, n := range readInts(path) {
+		if n2 := next(&r); n != n2 {
+			t.Fatalf("%s: expected %d at index %d, got %d", path, n, i, n2)
+		}
+	}
+}
+
+// Checks a generator against a test vector file of floats, which must match exactly
+func checkFloats(t *testing.T, path string, next func(r *Random) float64) {
+	r := NewRandom(1010)
+	for i, n := range readFloats(path) {
+		if n2 := next(&r); n != n2 {
+			t.Fatalf("%s: expected %v at index %d, got %v", path, n, i, n2)
+		}
+	}
+}
+
+func TestNextInt32(t *testing.T) {
+	checkInts(t, "s1010_nextInt.txt", func(r *Random) int64 { return int64(r.NextInt32()) })
+}
+
+func TestNextIntRange(t *testing.T) {
+	for _, bounds := range [][2]int32{{-8, 8}, {-5000, 100000}, {-2000000000, 2000000000}} {
+		path := fmt.Sprintf("s1010_nextInt_%d_%d.txt", bounds[0], bounds[1])
+		checkInts(t, path, func(r *Random) int64 { return int64(r.NextIntRange(bounds[0], bounds[1])) })
+	}
+}
+
+func TestNextLong(t *testing.T) {
+	checkInts(t, "s1010_nextLong.txt", func(r *Random) int64 { return r.NextLong() })
+}
+
+func TestNextLongBounded(t *testing.T) {
+	for _, bound := range []int64{1 << 40, 1000000007, 6000000000000000000} {
+		path := fmt.Sprintf("s1010_nextLong_%d.txt", bound)
+		checkInts(t, path, func(r *Random) int64 { return r.NextLongBounded(bound) })
+	}
+}
+
+func TestNextLongRange(t *testing.T) {
+	for _, bounds := range [][2]int64{{-1000000000000000000, 1000000000000000000}, {-9000000000000000000, 9000000000000000000}} {
+		path := fmt.Sprintf("s1010_nextLong_%d_%d.txt", bounds[0], bounds[1])
+		checkInts(t, path, func(r *Random) int64 { return r.NextLongRange(bounds[0], bounds[1]) })
+	}
+}
+
+func TestNextBoolean(t *testing.T) {
+	checkInts(t, "s1010_nextBoolean.txt", func(r *Random) int64 {
+		if r.NextBoolean() {
+			return 1
+		}
+		return 0
+	})
+}
+
+func TestNextFloat(t *testing.T) {
+	checkFloats(t, "s1010_nextFloat.txt", func(r *Random) float64 { return float64(r.NextFloat()) })
+}
+
+func TestNextDouble(t *testing.T) {
+	checkFloats(t, "s1010_nextDouble.txt", func(r *Random) float64 { return r.NextDouble() })
+}
+
+func TestNextGaussian(t *testing.T) {
+	checkFloats(t, "s1010_nextGaussian.txt", func(r *Random) float64 { return r.NextGaussian() })
+}
+
+func TestNextBytes(t *testing.T) {
+	data := readInts("s1010_nextBytes7.txt")
+	r := NewRandom(1010)
+	buf := make([]byte, 7)
+	for i := 0; i < len(data); i += len(buf) {
+		r.NextBytes(buf)
+		for j, b := range buf {
+			if data[i+j] != int64(int8(b)) {
+				t.Fatalf("Expected %d at index %d, got %d", data[i+j], i+j, int8(b))
+			}
+		}
+	}
+}
+
+// Values printed by Java itself
+func TestJavaValues(t *testing.T) {
+	r := NewRandom(0)
+	if v := r.NextInt32(); v != -1155484576 {
+		t.Errorf("new Random(0).nextInt(): expected -1155484576, got %d", v)
+	}
+	r = NewRandom(0)
+	if v := r.NextLong(); v != -4962768465676381896 {
+		t.Errorf("new Random(0).nextLong(): expected -4962768465676381896, got %d", v)
+	}
+	r = NewRandom(42)
+	if v := r.NextGaussian(); v != 1.1419053154730547 {
+		t.Errorf("new Random(42).nextGaussian(): expected 1.1419053154730547, got %v", v)
+	}
+}
+
+func TestSkip(t *testing.T) {
+	for _, n := range []int64{0, 1, 2, 1000, 123456789, 1 << 47} {
+		r1, r2 := NewRandom(1010), NewRandom(1010)
+		if n <= 1000 {
+			for i := int64(0); i < n; i++ {
+				r1.Next(32)
+			}
+		} else {
+			// Too many steps to take one at a time, so check that skipping in two parts agrees
+			r1.Skip(n / 3)
+			r1.Skip(n - n/3)
+		}
+		r2.Skip(n)
+		if r1.seed != r2.seed {
+			t.Errorf("Skip(%d): expected state %d, got %d", n, r1.seed, r2.seed)
+		}
+
+		r2.Skip(-n)
+		if r2.seed != NewRandom(1010).seed {
+			t.Errorf("Skip(-%d) did not undo Skip(%d)", n, n)
+		}
+	}
+
+	// A full period returns to the start
+	r := NewRandom(1010)
+	r.Skip(1 << 48)
+	if r.seed != NewRandom(1010).seed {
+		t.Error("Skipping a full period changed the state")
+	}
+}
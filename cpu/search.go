@@ -1,76 +1,104 @@
 package cpu
 
 import (
-	"fmt"
+	"context"
+	"errors"
+	"image"
 	"runtime"
 	"sync"
 
 	"github.com/vktec/slimy"
-	"github.com/vktec/slimy/util"
 )
 
-const SectionSize = 128
-
 type Searcher struct {
 	workerCount int
 	mask        Mask
+	edition     slimy.Edition
 }
 
-func NewSearcher(workerCount int, mask Mask) (*Searcher, error) {
-	return &Searcher{workerCount, mask}, nil
+// Creates a searcher for the mask image.
+// If weighted is true, each cell of the mask is weighted by the brightness of its pixel, as with NewWeightedMask.
+func NewSearcher(workerCount int, mask image.Image, edition slimy.Edition, weighted bool) (*Searcher, error) {
+	if mask.Bounds().Empty() {
+		return nil, errors.New("Mask image is empty")
+	}
+	m := NewMask(mask)
+	if weighted {
+		m = NewWeightedMask(mask)
+	}
+	if err := checkMaskBounds(m.Bounds()); err != nil {
+		return nil, err
+	}
+	return &Searcher{workerCount, m, edition}, nil
 }
 func (s *Searcher) Destroy() {}
 
-func (s *Searcher) Search(x0, z0, x1, z1 int32, threshold int, worldSeed int64) []slimy.Result {
-	w := World(worldSeed)
+func (s *Searcher) Search(x0, z0, x1, z1 int32, threshold slimy.Threshold, worldSeed int64) []slimy.Result {
+	// The mask was checked by NewSearcher, so this can't fail
+	results, _ := World{worldSeed, s.edition}.Search(s.workerCount, x0, z0, x1, z1, threshold, s.mask)
+	return results
+}
+
+func (s *Searcher) SearchContext(ctx context.Context, x0, z0, x1, z1 int32, threshold slimy.Threshold, worldSeed int64, opts slimy.Options) ([]slimy.Result, error) {
+	return World{worldSeed, s.edition}.SearchContext(ctx, s.workerCount, x0, z0, x1, z1, threshold, s.mask, opts)
+}
 
-	mw, mh := s.mask.Bounds()
-	if mw >= SectionSize || mh >= SectionSize {
-		panic("Mask bounds exceed section size")
-	}
+// Searches an area of the world for positions where the mask contains a number of slime chunks matching the threshold.
+// Every mask centre from x0, z0 up to but not including x1, z1 is checked.
+func (w World) Search(workerCount int, x0, z0, x1, z1 int32, threshold slimy.Threshold, mask Mask) ([]slimy.Result, error) {
+	return w.SearchContext(context.Background(), workerCount, x0, z0, x1, z1, threshold, mask, slimy.Options{})
+}
 
-	if s.workerCount <= 0 {
-		s.workerCount = runtime.GOMAXPROCS(0)
+// Like Search, but stops early if ctx is cancelled, returning the results found so far along with the context's error
+func (w World) SearchContext(ctx context.Context, workerCount int, x0, z0, x1, z1 int32, threshold slimy.Threshold, mask Mask, opts slimy.Options) ([]slimy.Result, error) {
+	results, err := searchWorlds(ctx, workerCount, x0, z0, x1, z1, threshold, mask, []World{w}, opts)
+	if results == nil {
+		return nil, err
 	}
+	return results[0], err
+}
 
-	sectionCh := make(chan *Section, 8)
-	resultCh := make(chan []slimy.Result, 8)
-	wgroup := new(sync.WaitGroup)
-	ctx := searchContext{w, threshold, s.mask, wgroup, sectionCh, resultCh}
-	go ctx.sendSections(x0, z0, x1, z1)
+// Creates an empty checkpoint for a search with this searcher
+func (s *Searcher) NewCheckpoint(x0, z0, x1, z1 int32, threshold slimy.Threshold, worldSeed int64) *Checkpoint {
+	return NewCheckpoint(World{worldSeed, s.edition}, x0, z0, x1, z1, threshold, s.mask)
+}
 
-	wgroup.Add(s.workerCount)
-	for i := 0; i < s.workerCount; i++ {
-		go ctx.search()
-	}
+// Like SearchContext, but records its progress in a checkpoint, as with World.SearchCheckpoint
+func (s *Searcher) SearchCheckpoint(ctx context.Context, cp *Checkpoint, save func(*Checkpoint) error, opts slimy.Options) ([]slimy.Result, error) {
+	return World{cp.Seed, s.edition}.SearchCheckpoint(ctx, s.workerCount, cp, s.mask, save, opts)
+}
 
-	var results []slimy.Result
-	for sectionResults := range resultCh {
-		start := len(results)
-		results = append(results, sectionResults...)
-		for i := start; i < len(results); i++ {
-			for j := i; j > 0; j-- {
-				if results[j].OrderBefore(results[j-1], threshold) {
-					results[j-1], results[j] = results[j], results[j-1]
-				} else {
-					break
-				}
-			}
-		}
+func (s *Searcher) SearchSeeds(ctx context.Context, x0, z0, x1, z1 int32, threshold slimy.Threshold, worldSeeds []int64, opts slimy.Options) ([]slimy.SeedResults, error) {
+	worlds := make([]World, len(worldSeeds))
+	for i, seed := range worldSeeds {
+		worlds[i] = World{seed, s.edition}
 	}
-	return results
+	return SearchSeeds(ctx, s.workerCount, x0, z0, x1, z1, threshold, s.mask, worlds, opts)
 }
 
-type searchContext struct {
-	world     World
-	threshold int
-	mask      Mask
-	wgroup    *sync.WaitGroup
-	sectionCh chan *Section
-	resultCh  chan []slimy.Result
+// Searches the same area of several worlds in one pass.
+// Each section of the area is only tiled once, and the parts of the slime chunk calculation that don't depend on the seed are shared between all the worlds.
+// Options.Stream is not supported, and Options.Limit applies to each seed separately.
+func SearchSeeds(ctx context.Context, workerCount int, x0, z0, x1, z1 int32, threshold slimy.Threshold, mask Mask, worlds []World, opts slimy.Options) ([]slimy.SeedResults, error) {
+	opts.Stream = nil
+
+	results, err := searchWorlds(ctx, workerCount, x0, z0, x1, z1, threshold, mask, worlds, opts)
+	if results == nil {
+		return nil, err
+	}
+	seedResults := make([]slimy.SeedResults, len(worlds))
+	for i, world := range worlds {
+		seedResults[i] = slimy.SeedResults{Seed: world.Seed, Results: results[i]}
+	}
+	return seedResults, err
 }
 
-func (ctx searchContext) sendSections(x0, z0, x1, z1 int32) {
+// Searches the same area of every world, returning the results for each world in the same order
+func searchWorlds(ctx context.Context, workerCount int, x0, z0, x1, z1 int32, threshold slimy.Threshold, mask Mask, worlds []World, opts slimy.Options) ([][]slimy.Result, error) {
+	if err := checkMaskBounds(mask.Bounds()); err != nil {
+		return nil, err
+	}
+
 	if x0 > x1 {
 		x0, x1 = x1, x0
 	}
@@ -78,108 +106,162 @@ func (ctx searchContext) sendSections(x0, z0, x1, z1 int32) {
 		z0, z1 = z1, z0
 	}
 
-	mx, mz := ctx.mask.Bounds()
-	shiftX := SectionSize - mx + 1
-	shiftZ := SectionSize - mz + 1
+	if workerCount <= 0 {
+		workerCount = runtime.GOMAXPROCS(0)
+	}
 
-	for x := x0; x < x1; x += shiftX {
-		for z := z0; z < z1; z += shiftZ {
-			ctx.sectionCh <- &Section{X: x, Z: z}
-		}
+	// Streamed results can't be limited, since they're sent as soon as they're found
+	limit := opts.Limit
+	if opts.Stream != nil {
+		limit = 0
 	}
-	close(ctx.sectionCh)
 
-	ctx.wgroup.Wait()
-	close(ctx.resultCh)
-}
+	area := int64(x1-x0) * int64(z1-z0)
+	progress := slimy.NewProgressReporter(opts.Progress, area*int64(len(worlds)))
+	sectionCh := make(chan *Section, 8)
+	resultCh := make(chan worldResults, 8)
+	wgroup := new(sync.WaitGroup)
+	sctx := searchContext{worlds, threshold, mask.compile(), x1, z1, limit, ctx.Done(), progress, wgroup, sectionCh, resultCh, nil}
+	// The workers must be counted before sendSections can wait for them
+	wgroup.Add(workerCount)
+	go sctx.sendSections(x0, z0)
+	for i := 0; i < workerCount; i++ {
+		go sctx.search()
+	}
 
-func (ctx searchContext) search() {
-	for sec := range ctx.sectionCh {
-		sec.Compute(ctx.world)
-		results := sec.Search(ctx.mask, ctx.threshold)
-		if len(results) > 0 {
-			ctx.resultCh <- results
+	results := make([][]slimy.Result, len(worlds))
+	tops := make([]*slimy.TopK, len(worlds))
+	if limit > 0 {
+		for i := range tops {
+			tops[i] = slimy.NewTopK(limit, threshold.Order())
+		}
+	}
+	for sectionResults := range resultCh {
+		i := sectionResults.world
+		switch {
+		case opts.Stream != nil:
+			for _, result := range sectionResults.results {
+				opts.Stream(result)
+			}
+		case tops[i] != nil:
+			tops[i].Add(sectionResults.results...)
+		default:
+			results[i] = append(results[i], sectionResults.results...)
 		}
 	}
-	ctx.wgroup.Done()
-}
-
-type Section struct {
-	X, Z  int32
-	Slime [SectionSize * SectionSize]bool
-}
 
-func (sec *Section) Compute(world World) {
-	for z := int32(0); z < SectionSize; z++ {
-		for x := int32(0); x < SectionSize; x++ {
-			sec.Set(x, z, world.CalcChunk(sec.X+x, sec.Z+z))
+	for i := range results {
+		if tops[i] != nil {
+			results[i] = tops[i].Results()
+		} else {
+			slimy.SortResults(results[i], threshold.Order())
 		}
 	}
+	progress.Finish()
+	return results, ctx.Err()
 }
 
-func (sec *Section) Search(mask Mask, threshold int) (results []slimy.Result) {
-	w, h := mask.Bounds()
-	offX, offZ := sec.X+w/2, sec.Z+h/2
-	x1, z1 := SectionSize-w, SectionSize-h
+// Results from one of the worlds being searched
+type worldResults struct {
+	world   int // Index into searchContext.worlds
+	tile    int // Index of the section the results are from, if tiles are being tracked
+	results []slimy.Result
+}
 
-	for z := int32(0); z < z1; z++ {
-		for x := int32(0); x < x1; x++ {
-			// TODO: avoid checking the full mask area every time
-			//       This can be done by adding the new and subtracting the old chunks
-			count := sec.CheckMask(x, z, mask)
-			if checkThreshold(threshold, int(count)) {
-				results = append(results, slimy.Result{x + offX, z + offZ, count})
+type searchContext struct {
+	worlds    []World
+	threshold slimy.Threshold
+	mask      *compiledMask
+	x1, z1    int32 // End of the search area
+	limit     int   // If positive, each worker only sends its best results once it's finished
+	done      <-chan struct{}
+	progress  *slimy.ProgressReporter
+	wgroup    *sync.WaitGroup
+	sectionCh chan *Section
+	resultCh  chan worldResults
+	tiles     *tileTracker // If not nil, finished tiles are skipped, and every searched tile is reported even if it has no results
+}
+
+// Sends sections covering every mask centre from x0, z0 to the end of the search area.
+// Each section holds the centres of the masks that fit entirely within it, so neighbouring sections overlap by the mask size minus one.
+func (ctx searchContext) sendSections(x0, z0 int32) {
+	offX, offZ := ctx.mask.w/2, ctx.mask.h/2
+sections:
+	for x := x0; x < ctx.x1; x += ctx.mask.stepX() {
+		for z := z0; z < ctx.z1; z += ctx.mask.stepZ() {
+			if ctx.tiles != nil && ctx.tiles.skip(x, z) {
+				ctx.progress.Add(int64(min32(ctx.mask.stepX(), ctx.x1-x)) * int64(min32(ctx.mask.stepZ(), ctx.z1-z)))
+				continue
+			}
+			select {
+			case ctx.sectionCh <- getSection(x-offX, z-offZ, ctx.mask.size):
+			case <-ctx.done:
+				break sections
 			}
 		}
 	}
-	return results
+	close(ctx.sectionCh)
+
+	ctx.wgroup.Wait()
+	close(ctx.resultCh)
 }
-func checkThreshold(threshold, count int) bool {
-	if threshold < 0 {
-		return int(count) <= -threshold
-	} else {
-		return int(count) >= threshold
+
+func (ctx searchContext) search() {
+	tops := make([]*slimy.TopK, len(ctx.worlds))
+	if ctx.limit > 0 {
+		for i := range tops {
+			tops[i] = slimy.NewTopK(ctx.limit, ctx.threshold.Order())
+		}
 	}
-}
 
-func (sec *Section) CheckMask(x0, z0 int32, mask Mask) (count uint) {
-	w, h := mask.Bounds()
-	for z := int32(0); z < h; z++ {
-		for x := int32(0); x < w; x++ {
-			if sec.Get(x+x0, z+z0) && mask.Query(x, z) {
-				count++
+	var terms chunkTerms
+	for sec := range ctx.sectionCh {
+		terms.compute(sec)
+		// Don't report centres beyond the end of the search area
+		w := min32(ctx.mask.stepX(), ctx.x1-(sec.X+ctx.mask.w/2))
+		h := min32(ctx.mask.stepZ(), ctx.z1-(sec.Z+ctx.mask.h/2))
+
+		for i, world := range ctx.worlds {
+			if ctx.cancelled() {
+				// Skip any sections that were queued before the search was cancelled
+				break
+			}
+
+			sec.computeWith(world, &terms)
+			results := sec.search(ctx.mask, ctx.threshold, w, h)
+			ctx.progress.Add(int64(w) * int64(h))
+			switch {
+			case ctx.tiles != nil:
+				ctx.resultCh <- worldResults{i, ctx.tiles.index(sec.X+ctx.mask.w/2, sec.Z+ctx.mask.h/2), results}
+			case tops[i] != nil:
+				tops[i].Add(results...)
+			case len(results) > 0:
+				ctx.resultCh <- worldResults{i, 0, results}
 			}
 		}
+		putSection(sec)
 	}
-	return count
-}
 
-func secIdx(x, z int32) int {
-	util.Assert(x < SectionSize, "x out of range")
-	util.Assert(z < SectionSize, "z out of range")
-	return int(SectionSize*z + x)
-}
-
-func (sec *Section) Set(x, z int32, v bool) {
-	sec.Slime[secIdx(x, z)] = v
+	for i, top := range tops {
+		if top != nil {
+			ctx.resultCh <- worldResults{i, 0, top.Results()}
+		}
+	}
+	ctx.wgroup.Done()
 }
 
-func (sec *Section) Get(x, z int32) bool {
-	return sec.Slime[secIdx(x, z)]
+func (ctx searchContext) cancelled() bool {
+	select {
+	case <-ctx.done:
+		return true
+	default:
+		return false
+	}
 }
 
-func (sec *Section) Print() {
-	for z := int32(0); z < SectionSize; z++ {
-		for x := int32(0); x < SectionSize; x++ {
-			if x > 0 {
-				fmt.Print(" ")
-			}
-			if sec.Get(x, z) {
-				fmt.Print("x")
-			} else {
-				fmt.Print(" ")
-			}
-		}
-		fmt.Print("\n")
+func min32(a, b int32) int32 {
+	if a < b {
+		return a
 	}
+	return b
 }
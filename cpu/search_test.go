@@ -1,62 +1,442 @@
 package cpu
 
 import (
+	"context"
+	"image"
+	"image/color"
+	"math"
+	"math/rand"
+	"sort"
 	"testing"
+	"time"
 
 	"github.com/vktec/slimy"
+	"github.com/vktec/slimy/util"
 )
 
+// Weighted scores are summed in a different order by the brute force search, so may differ by rounding error
+const scoreTolerance = 1e-9
+
 func checkResults(t *testing.T, got, expected []slimy.Result) {
 	if len(got) != len(expected) {
 		t.Fatalf("Wrong number of results: expected %d, got %d", len(expected), len(got))
 	}
 
 	for i := range got {
-		if got[i] != expected[i] {
-			t.Fatalf("Incorrect result at index %d: expected %v, got %v", i, expected[i], got[i])
+		g, e := got[i], expected[i]
+		if g.X != e.X || g.Z != e.Z || g.Count != e.Count || math.Abs(g.Score-e.Score) > scoreTolerance {
+			t.Fatalf("Incorrect result at index %d: expected %v, got %v", i, e, g)
+		}
+	}
+}
+
+func donut(innerRad, outerRad int) Mask {
+	return NewMask(util.GenDonut(innerRad, outerRad))
+}
+
+// A non-square mask with bounds that don't start at the origin
+func offCentreMask() image.Image {
+	img := image.NewGray(image.Rect(3, -2, 30, 9))
+	for y := -2; y < 9; y++ {
+		for x := 3; x < 30; x++ {
+			if (x*y)%7 < 3 {
+				img.SetGray(x, y, color.Gray{255})
+			}
+		}
+	}
+	return img
+}
+
+func TestNewMask(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(-1, 4, 4, 5))
+	img.SetNRGBA(-1, 4, color.NRGBA{255, 255, 255, 255})
+	img.SetNRGBA(0, 4, color.NRGBA{0, 0, 200, 255})
+	img.SetNRGBA(1, 4, color.NRGBA{255, 0, 0, 100})
+	img.SetNRGBA(2, 4, color.NRGBA{100, 100, 100, 255})
+	img.SetNRGBA(3, 4, color.NRGBA{0, 0, 0, 255})
+	expected := []bool{true, true, false, false, false}
+
+	mask := NewMask(img)
+	if w, h := mask.Bounds(); w != 5 || h != 1 {
+		t.Fatalf("Incorrect mask bounds: expected 5x1, got %dx%d", w, h)
+	}
+	for x, v := range expected {
+		if mask.Query(int32(x), 0) != v {
+			t.Errorf("Incorrect mask cell at %d: expected %v", x, v)
 		}
 	}
 }
 
 func TestSearchMaskTooBig(t *testing.T) {
-	mask := Mask{64, 1}
-	world := World(1)
-
-	func() {
-		defer func() {
-			if err := recover(); err == nil {
-				t.Error("Expected mask bounds error")
-			} else if err != "Mask bounds exceed section size" {
-				panic(err)
-			}
-		}()
-		world.Search(0, 0, 0, 1, 1, 0, mask)
-	}()
+	img := image.NewGray(image.Rect(0, 0, MaxMaskSize+1, 1))
+	if _, err := NewSearcher(0, img, slimy.JavaEdition, false); err == nil {
+		t.Error("Expected mask bounds error")
+	}
+	if _, err := JavaWorld(1).Search(0, 0, 0, 1, 1, slimy.AtLeast(0), NewMask(img)); err == nil {
+		t.Error("Expected mask bounds error")
+	}
+}
+
+func TestSectionSize(t *testing.T) {
+	for _, size := range []int32{1, 100, 2000, 4096, 5000, MaxMaskSize} {
+		sec := sectionSize(size, 1)
+		if sec%64 != 0 || sec > maxSectionSize || sec-size+1 < size {
+			t.Errorf("Mask size %d: section size %d leaves too little to search", size, sec)
+		}
+	}
+}
+
+func TestSearchLargeMask(t *testing.T) {
+	// Masks wider than the default section size use bigger sections
+	mask := donut(60, 64)
+	world := JavaWorld(1)
+	expected := bruteForceSearch(world, -20, 10, 20, 40, slimy.AtLeast(45), mask)
+	results, err := world.Search(0, -20, 10, 20, 40, slimy.AtLeast(45), mask)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkResults(t, results, expected)
 }
 
 func BenchmarkSearch100(b *testing.B) {
-	mask := Mask{8, 1}
-	world := World(1)
+	mask := donut(1, 8)
+	world := JavaWorld(1)
 
 	for i := 0; i < b.N; i++ {
-		world.Search(0, -100, -100, 0, 0, 1_000_000, mask)
+		world.Search(0, -100, -100, 0, 0, slimy.AtLeast(1_000_000), mask)
 	}
 }
 
 func BenchmarkSearch1k(b *testing.B) {
-	mask := Mask{8, 1}
-	world := World(1)
+	mask := donut(1, 8)
+	world := JavaWorld(1)
 
 	for i := 0; i < b.N; i++ {
-		world.Search(0, -500, -500, 500, 500, 1_000_000, mask)
+		world.Search(0, -500, -500, 500, 500, slimy.AtLeast(1_000_000), mask)
 	}
 }
 
 func BenchmarkSearch5k(b *testing.B) {
-	mask := Mask{8, 1}
-	world := World(1)
+	mask := donut(1, 8)
+	world := JavaWorld(1)
+
+	for i := 0; i < b.N; i++ {
+		world.Search(0, 0, 0, 5000, 5000, slimy.AtLeast(1_000_000), mask)
+	}
+}
+
+func TestSectionSearch(t *testing.T) {
+	masks := []Mask{donut(1, 8), donut(0, 8), donut(2, 3), donut(12, 24), donut(0, 0), NewMask(offCentreMask())}
+	for _, mask := range masks {
+		sec := NewSection(-300, 1200, DefaultSectionSize)
+		sec.Compute(JavaWorld(1))
+
+		var expected []slimy.Result
+		w, h := mask.Bounds()
+		for z := int32(0); z <= sec.Size-h; z++ {
+			for x := int32(0); x <= sec.Size-w; x++ {
+				count := sec.CheckMask(x, z, mask)
+				if count >= 3 {
+					expected = append(expected, slimy.Result{X: sec.X + x + w/2, Z: sec.Z + z + h/2, Count: count, Score: float64(count)})
+				}
+			}
+		}
+
+		checkResults(t, sec.Search(mask, slimy.AtLeast(3)), expected)
+	}
+}
+
+// Checks every centre in the area individually
+func bruteForceSearch(world World, x0, z0, x1, z1 int32, threshold slimy.Threshold, mask Mask) (results []slimy.Result) {
+	w, h := mask.Bounds()
+	for z := z0; z < z1; z++ {
+		for x := x0; x < x1; x++ {
+			count, score := uint(0), 0.0
+			for mz := int32(0); mz < h; mz++ {
+				for mx := int32(0); mx < w; mx++ {
+					if mask.Query(mx, mz) && world.CalcChunk(x-w/2+mx, z-h/2+mz) {
+						count++
+						// Sum whole weights, so that scores on the threshold's bounds are exact
+						score += math.Round(mask.Weight(mx, mz) * util.MaxMaskWeight)
+					}
+				}
+			}
+			score /= util.MaxMaskWeight
+			if threshold.CheckScore(score) {
+				results = append(results, slimy.Result{X: x, Z: z, Count: count, Score: score})
+			}
+		}
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].OrderBefore(results[j], threshold.Order())
+	})
+	return results
+}
+
+func TestSearchTiling(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	masks := []Mask{donut(1, 3), donut(0, 0), NewMask(offCentreMask())}
+	for i := 0; i < 12; i++ {
+		world := JavaWorld(rng.Int63())
+		mask := masks[i%len(masks)]
+		x0, z0 := rng.Int31n(2000)-1000, rng.Int31n(2000)-1000
+		x1, z1 := x0+rng.Int31n(300), z0+rng.Int31n(300)
+		thresholds := []slimy.Threshold{slimy.AtLeast(4), slimy.AtMost(1), slimy.Between(2, 3)}
+		threshold := thresholds[i%len(thresholds)]
+
+		expected := bruteForceSearch(world, x0, z0, x1, z1, threshold, mask)
+		results, _ := world.Search(3, x0, z0, x1, z1, threshold, mask)
+		checkResults(t, results, expected)
+		// Bounds given the wrong way round should cover the same area
+		results, _ = world.Search(3, x1, z1, x0, z0, threshold, mask)
+		checkResults(t, results, expected)
+	}
+}
+
+func TestSearchBedrock(t *testing.T) {
+	world := BedrockWorld()
+	mask := donut(1, 3)
+	expected := bruteForceSearch(world, -100, 20, -20, 90, slimy.AtLeast(5), mask)
+	results, err := world.Search(3, -100, 20, -20, 90, slimy.AtLeast(5), mask)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkResults(t, results, expected)
+}
+
+// A mask whose weights fall off with distance from the centre
+func weightedMask(rad int) Mask {
+	img := image.NewGray(image.Rect(0, 0, 2*rad+1, 2*rad+1))
+	for y := -rad; y <= rad; y++ {
+		for x := -rad; x <= rad; x++ {
+			d := math.Hypot(float64(x), float64(y))
+			if d <= float64(rad) {
+				img.SetGray(x+rad, y+rad, color.Gray{uint8(255 - 200*d/float64(rad))})
+			}
+		}
+	}
+	return NewWeightedMask(img)
+}
+
+func TestSearchWeighted(t *testing.T) {
+	world := JavaWorld(7)
+	mask := weightedMask(6)
+	for _, threshold := range []slimy.Threshold{slimy.AtLeast(6), slimy.AtMost(1), slimy.Between(3, 4)} {
+		results, err := world.Search(3, -150, -50, 50, 100, threshold, mask)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 1; i < len(results); i++ {
+			if results[i].OrderBefore(results[i-1], threshold.Order()) {
+				t.Fatalf("Results out of order at index %d", i)
+			}
+		}
+
+		// Rounding error can swap results with equal scores, so compare them by position
+		expected := bruteForceSearch(world, -150, -50, 50, 100, threshold, mask)
+		byPos := func(r []slimy.Result) func(i, j int) bool {
+			return func(i, j int) bool { return r[i].Z < r[j].Z || r[i].Z == r[j].Z && r[i].X < r[j].X }
+		}
+		sort.Slice(results, byPos(results))
+		sort.Slice(expected, byPos(expected))
+		checkResults(t, results, expected)
+	}
+}
+
+func TestWeightedMaskBinary(t *testing.T) {
+	// A black and white mask weights every cell fully, so scores are counts
+	img := util.GenDonut(1, 4)
+	binary, weighted := NewMask(img), NewWeightedMask(img)
+	expected, _ := JavaWorld(3).Search(3, -60, -60, 60, 60, slimy.AtLeast(6), binary)
+	results, _ := JavaWorld(3).Search(3, -60, -60, 60, 60, slimy.AtLeast(6), weighted)
+	checkResults(t, results, expected)
+	for _, r := range results {
+		if r.Score != float64(r.Count) {
+			t.Fatalf("Score %v does not match count %d", r.Score, r.Count)
+		}
+	}
+}
+
+func benchmarkSectionSearch(b *testing.B, mask Mask) {
+	sec := NewSection(0, 0, DefaultSectionSize)
+	sec.Compute(JavaWorld(1))
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		sec.Search(mask, slimy.AtLeast(1_000_000))
+	}
+}
+
+// Checks the full mask area at every position, for comparison with the incremental search
+func benchmarkSectionCheckMask(b *testing.B, mask Mask) {
+	sec := NewSection(0, 0, DefaultSectionSize)
+	sec.Compute(JavaWorld(1))
+	b.ResetTimer()
+
+	cm := mask.compile()
+	for i := 0; i < b.N; i++ {
+		for z := int32(0); z < sec.Size-cm.h; z++ {
+			for x := int32(0); x < sec.Size-cm.w; x++ {
+				sec.checkMask(x, z, cm)
+			}
+		}
+	}
+}
+
+func BenchmarkSectionSearch8(b *testing.B)     { benchmarkSectionSearch(b, donut(1, 8)) }
+func BenchmarkSectionCheckMask8(b *testing.B)  { benchmarkSectionCheckMask(b, donut(1, 8)) }
+func BenchmarkSectionSearch32(b *testing.B)    { benchmarkSectionSearch(b, donut(1, 32)) }
+func BenchmarkSectionCheckMask32(b *testing.B) { benchmarkSectionCheckMask(b, donut(1, 32)) }
+
+func BenchmarkSearchLargeMask1k(b *testing.B) {
+	mask := donut(1, 48)
+	world := JavaWorld(1)
 
 	for i := 0; i < b.N; i++ {
-		world.Search(0, 0, 0, 5000, 5000, 1_000_000, mask)
+		world.Search(0, -500, -500, 500, 500, slimy.AtLeast(1_000_000), mask)
+	}
+}
+
+func TestSectionGetSet(t *testing.T) {
+	sec := NewSection(0, 0, 192)
+	sec.Compute(JavaWorld(1))
+
+	for z := int32(0); z < sec.Size; z++ {
+		for x := int32(0); x < sec.Size; x++ {
+			if sec.Get(x, z) != JavaWorld(1).CalcChunk(x, z) {
+				t.Fatalf("Incorrect chunk at %d, %d", x, z)
+			}
+			sec.Set(x, z, (x+z)%3 == 0)
+		}
+	}
+	for z := int32(0); z < sec.Size; z++ {
+		for x := int32(0); x < sec.Size; x++ {
+			if sec.Get(x, z) != ((x+z)%3 == 0) {
+				t.Fatalf("Incorrect chunk at %d, %d after Set", x, z)
+			}
+		}
+	}
+}
+
+func TestSearchProgress(t *testing.T) {
+	var last slimy.Progress
+	calls := 0
+	opts := slimy.Options{Progress: func(p slimy.Progress) {
+		if p.Scanned < last.Scanned {
+			t.Errorf("Progress went backwards: %d after %d", p.Scanned, last.Scanned)
+		}
+		last = p
+		calls++
+	}}
+
+	if _, err := JavaWorld(1).SearchContext(context.Background(), 2, -100, -100, 200, 150, slimy.AtLeast(40), donut(1, 8), opts); err != nil {
+		t.Fatal(err)
+	}
+	if calls == 0 {
+		t.Fatal("Progress was never reported")
+	}
+	if last.Total != 300*250 || last.Scanned != last.Total {
+		t.Errorf("Incorrect final progress: %+v", last)
+	}
+}
+
+func TestSearchCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var scanned int64
+	opts := slimy.Options{Progress: func(p slimy.Progress) {
+		scanned = p.Scanned
+		cancel()
+	}}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, err := JavaWorld(1).SearchContext(ctx, 2, -50000, -50000, 50000, 50000, slimy.AtLeast(1_000_000), donut(1, 8), opts)
+		if err != context.Canceled {
+			t.Errorf("Expected %v, got %v", context.Canceled, err)
+		}
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("Search did not stop after being cancelled")
+	}
+	if scanned >= 100000*100000 {
+		t.Error("Cancelled search scanned the whole area")
+	}
+}
+
+func TestSearchStream(t *testing.T) {
+	world := JavaWorld(1)
+	mask := donut(1, 8)
+	expected, _ := world.Search(2, -300, -200, 300, 100, slimy.AtLeast(32), mask)
+
+	var streamed []slimy.Result
+	opts := slimy.Options{Stream: func(result slimy.Result) {
+		streamed = append(streamed, result)
+	}}
+	results, err := world.SearchContext(context.Background(), 2, -300, -200, 300, 100, slimy.AtLeast(32), mask, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 0 {
+		t.Errorf("Expected no returned results when streaming, got %d", len(results))
+	}
+
+	sort.Slice(streamed, func(i, j int) bool {
+		return streamed[i].OrderBefore(streamed[j], slimy.Descending)
+	})
+	checkResults(t, streamed, expected)
+}
+
+func TestSearchLimit(t *testing.T) {
+	world := JavaWorld(1)
+	mask := donut(1, 8)
+	for _, threshold := range []slimy.Threshold{slimy.AtLeast(30), slimy.AtMost(8), slimy.Between(25, 27)} {
+		all, _ := world.Search(3, -300, -200, 300, 100, threshold, mask)
+		if len(all) < 20 {
+			t.Fatalf("Not enough results to test with: %d", len(all))
+		}
+
+		for _, limit := range []int{1, 7, len(all) + 5} {
+			expected := all
+			if limit < len(all) {
+				expected = all[:limit]
+			}
+			results, err := world.SearchContext(context.Background(), 3, -300, -200, 300, 100, threshold, mask, slimy.Options{Limit: limit})
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkResults(t, results, expected)
+		}
+	}
+}
+
+func TestSearchSeeds(t *testing.T) {
+	mask := donut(1, 8)
+	worlds := []World{JavaWorld(1), JavaWorld(-7), BedrockWorld(), JavaWorld(123456789), JavaWorld(1)}
+	threshold := slimy.AtLeast(30)
+	for _, limit := range []int{0, 3} {
+		var last slimy.Progress
+		opts := slimy.Options{Limit: limit, Progress: func(p slimy.Progress) { last = p }}
+		results, err := SearchSeeds(context.Background(), 3, -200, -150, 100, 50, threshold, mask, worlds, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(results) != len(worlds) {
+			t.Fatalf("Expected results for %d seeds, got %d", len(worlds), len(results))
+		}
+		if last.Total != 300*200*int64(len(worlds)) || last.Scanned != last.Total {
+			t.Errorf("Incorrect final progress: %+v", last)
+		}
+
+		for i, world := range worlds {
+			if results[i].Seed != world.Seed {
+				t.Fatalf("Incorrect seed at index %d: expected %d, got %d", i, world.Seed, results[i].Seed)
+			}
+			expected, _ := world.SearchContext(context.Background(), 3, -200, -150, 100, 50, threshold, mask, slimy.Options{Limit: limit})
+			checkResults(t, results[i].Results, expected)
+		}
 	}
 }
@@ -0,0 +1,275 @@
+package cpu
+
+import (
+	"fmt"
+	"math/bits"
+	"sync"
+
+	"github.com/vktec/slimy"
+	"github.com/vktec/slimy/util"
+)
+
+// The size of sections used for small masks.
+// Larger masks use larger sections, so that the overlap between neighbouring sections stays small.
+const DefaultSectionSize = 128
+
+// The largest section size, which is 32 MiB of bits.
+// A search holds one section per worker, and a few more waiting to be searched, so larger sections would need gigabytes of memory.
+const maxSectionSize = 16384
+
+// The largest mask width or height the CPU searcher supports.
+// A section must be at least twice as large as the mask for most of it to be searched, so this is limited by maxSectionSize.
+const MaxMaskSize = maxSectionSize / 2
+
+func checkMaskBounds(w, h int32) error {
+	if w > MaxMaskSize || h > MaxMaskSize {
+		return fmt.Errorf("Mask bounds %dx%d exceed the maximum size of %d, since larger masks need sections of more than %d MiB each", w, h, MaxMaskSize, maxSectionSize*maxSectionSize/8>>20)
+	}
+	return nil
+}
+
+// Picks a section size for a mask of the given dimensions.
+// Sections are 4 times the size of the mask, so that little of each is overlapped by its neighbours, up to maxSectionSize.
+func sectionSize(w, h int32) int32 {
+	size := 4 * w
+	if h > w {
+		size = 4 * h
+	}
+	if size < DefaultSectionSize {
+		size = DefaultSectionSize
+	}
+	if size > maxSectionSize {
+		size = maxSectionSize
+	}
+	return (size + 63) &^ 63
+}
+
+// Sections are large, so they are reused rather than allocated for every part of the search area.
+// Each section size has its own pool.
+var sectionPools sync.Map // map[int32]*sync.Pool
+
+func getSectionPool(size int32) *sync.Pool {
+	if pool, ok := sectionPools.Load(size); ok {
+		return pool.(*sync.Pool)
+	}
+	pool, _ := sectionPools.LoadOrStore(size, &sync.Pool{New: func() interface{} {
+		return NewSection(0, 0, size)
+	}})
+	return pool.(*sync.Pool)
+}
+
+func getSection(x, z, size int32) *Section {
+	sec := getSectionPool(size).Get().(*Section)
+	sec.X, sec.Z = x, z
+	return sec
+}
+
+func putSection(sec *Section) {
+	getSectionPool(sec.Size).Put(sec)
+}
+
+// A square of chunks, stored as one bit per chunk.
+// Bit i of word j in a row holds the chunk at x = 64*j + i.
+type Section struct {
+	X, Z int32
+	Size int32
+
+	// Each row has an extra word that is always zero, so bits can be extracted from anywhere in the row without bounds checks
+	stride int32
+	slime  []uint64
+}
+
+// Creates a section. The size must be a multiple of 64
+func NewSection(x, z, size int32) *Section {
+	util.Assert(size > 0 && size%64 == 0, "Section size must be a positive multiple of 64")
+	stride := size/64 + 1
+	return &Section{x, z, size, stride, make([]uint64, stride*size)}
+}
+
+func (sec *Section) row(z int32) []uint64 {
+	return sec.slime[z*sec.stride : (z+1)*sec.stride]
+}
+
+func (sec *Section) Compute(world World) {
+	var terms chunkTerms
+	terms.compute(sec)
+	sec.computeWith(world, &terms)
+}
+
+// The seed-independent parts of the slime chunk calculation for each column and row of a section
+type chunkTerms struct {
+	x, z []int64
+}
+
+func (terms *chunkTerms) compute(sec *Section) {
+	if int32(len(terms.x)) != sec.Size {
+		terms.x = make([]int64, sec.Size)
+		terms.z = make([]int64, sec.Size)
+	}
+	for i := int32(0); i < sec.Size; i++ {
+		terms.x[i] = chunkTermX(sec.X + i)
+		terms.z[i] = chunkTermZ(sec.Z + i)
+	}
+}
+
+// Computes the section's slime chunks, using terms computed for the same section if the world is from Java Edition
+func (sec *Section) computeWith(world World, terms *chunkTerms) {
+	if world.Edition == slimy.BedrockEdition {
+		sec.computeBedrock()
+		return
+	}
+
+	for z := int32(0); z < sec.Size; z++ {
+		row := sec.row(z)
+		tz := terms.z[z]
+		for j := int32(0); j < sec.stride-1; j++ {
+			var word uint64
+			for i, tx := range terms.x[64*j : 64*j+64] {
+				if world.calcChunkTerm(tx + tz) {
+					word |= 1 << i
+				}
+			}
+			row[j] = word
+		}
+	}
+}
+
+func (sec *Section) computeBedrock() {
+	for z := int32(0); z < sec.Size; z++ {
+		row := sec.row(z)
+		for j := int32(0); j < sec.stride-1; j++ {
+			var word uint64
+			for i := int32(0); i < 64; i++ {
+				if calcBedrockChunk(sec.X+64*j+i, sec.Z+z) {
+					word |= 1 << i
+				}
+			}
+			row[j] = word
+		}
+	}
+}
+
+// Searches every position at which the mask fits entirely within the section
+func (sec *Section) Search(mask Mask, threshold slimy.Threshold) (results []slimy.Result) {
+	cm := mask.compile()
+	return sec.search(cm, threshold, sec.Size-cm.w+1, sec.Size-cm.h+1)
+}
+
+// Rather than checking the full mask area at every position, the count is kept up to date by adding the chunks that enter the mask and subtracting those that leave it.
+// The mask slides down the first column, then along each row from there.
+// Small masks are cheaper to count with a few popcounts per row, so those are checked in full along each row instead.
+// Only the first x1 by z1 positions are searched.
+func (sec *Section) search(mask *compiledMask, threshold slimy.Threshold, x1, z1 int32) (results []slimy.Result) {
+	offX, offZ := sec.X+mask.w/2, sec.Z+mask.h/2
+	if x1 <= 0 || z1 <= 0 {
+		return nil
+	}
+
+	// Weights are at most 1, so a weighted score is never more than the count
+	minScore, _ := threshold.Bounds()
+
+	colCount := int(sec.checkMask(0, 0, mask))
+	for z := int32(0); z < z1; z++ {
+		if z > 0 {
+			colCount += sec.countEdge(0, z-1, mask.edges.addZ) - sec.countEdge(0, z-1, mask.edges.subZ)
+		}
+
+		count := colCount
+		for x := int32(0); x < x1; x++ {
+			if x > 0 {
+				if mask.incremental {
+					count += sec.countEdge(x-1, z, mask.edges.addX) - sec.countEdge(x-1, z, mask.edges.subX)
+				} else {
+					count = int(sec.checkMask(x, z, mask))
+				}
+			}
+			score := float64(count)
+			if mask.planes != nil {
+				if uint(count) < minScore {
+					continue
+				}
+				score = sec.weightedScore(x, z, mask)
+			}
+			if threshold.CheckScore(score) {
+				results = append(results, slimy.Result{X: x + offX, Z: z + offZ, Count: uint(count), Score: score})
+			}
+		}
+	}
+	return results
+}
+
+// Counts the slime chunks at the given points, relative to x0, z0
+func (sec *Section) countEdge(x0, z0 int32, edge []maskPoint) (count int) {
+	for _, p := range edge {
+		x := uint32(x0 + p.x)
+		count += int(sec.slime[(z0+p.z)*sec.stride+int32(x/64)] >> (x % 64) & 1)
+	}
+	return count
+}
+
+func (sec *Section) CheckMask(x0, z0 int32, mask Mask) (count uint) {
+	return sec.checkMask(x0, z0, mask.compile())
+}
+
+func (sec *Section) checkMask(x0, z0 int32, mask *compiledMask) (count uint) {
+	for z := int32(0); z < mask.h; z++ {
+		row := sec.row(z + z0)
+		for j, m := range mask.row(z) {
+			count += uint(bits.OnesCount64(extractBits(row, x0+64*int32(j)) & m))
+		}
+	}
+	return count
+}
+
+// Returns the weighted sum of the slime chunks in a weighted mask
+func (sec *Section) weightedScore(x0, z0 int32, mask *compiledMask) float64 {
+	sum := uint(0)
+	for b, plane := range mask.planes {
+		if plane != nil {
+			sum += sec.checkMask(x0, z0, plane) << b
+		}
+	}
+	return float64(sum) / util.MaxMaskWeight
+}
+
+// Returns the 64 bits of the row starting at x
+func extractBits(row []uint64, x int32) uint64 {
+	i, shift := uint32(x)/64, uint32(x)%64
+	return row[i]>>shift | row[i+1]<<(64-shift)
+}
+
+func (sec *Section) checkCoord(x, z int32) {
+	util.Assert(0 <= x && x < sec.Size, "x out of range")
+	util.Assert(0 <= z && z < sec.Size, "z out of range")
+}
+
+func (sec *Section) Set(x, z int32, v bool) {
+	sec.checkCoord(x, z)
+	row, bit := sec.row(z), uint64(1)<<(uint32(x)%64)
+	if v {
+		row[x/64] |= bit
+	} else {
+		row[x/64] &^= bit
+	}
+}
+
+func (sec *Section) Get(x, z int32) bool {
+	sec.checkCoord(x, z)
+	return sec.row(z)[x/64]>>(uint32(x)%64)&1 != 0
+}
+
+func (sec *Section) Print() {
+	for z := int32(0); z < sec.Size; z++ {
+		for x := int32(0); x < sec.Size; x++ {
+			if x > 0 {
+				fmt.Print(" ")
+			}
+			if sec.Get(x, z) {
+				fmt.Print("x")
+			} else {
+				fmt.Print(" ")
+			}
+		}
+		fmt.Print("\n")
+	}
+}
@@ -1,14 +1,46 @@
 package cpu
 
-type World int64
+import "github.com/vktec/slimy"
+
+type World struct {
+	Seed    int64
+	Edition slimy.Edition
+}
+
+func JavaWorld(seed int64) World {
+	return World{seed, slimy.JavaEdition}
+}
+
+// Bedrock slime chunks don't depend on the seed, so every Bedrock world is the same
+func BedrockWorld() World {
+	return World{0, slimy.BedrockEdition}
+}
 
 func (w World) CalcChunk(x, z int32) bool {
-	seed := int64(w) +
-		int64(x*x*4987142) +
-		int64(x*5947611) +
-		int64(z*z)*4392871 + // sic
-		int64(z*389711)
+	if w.Edition == slimy.BedrockEdition {
+		return calcBedrockChunk(x, z)
+	}
+	return w.calcChunkTerm(chunkTermX(x) + chunkTermZ(z))
+}
+
+// Checks whether a chunk is a slime chunk, given the sum of its x and z terms
+func (w World) calcChunkTerm(term int64) bool {
+	seed := w.Seed + term
 	seed ^= 987234911
 	r := NewRandom(seed)
 	return r.NextInt(10) == 0
 }
+
+// The slime chunk seed is the world seed plus an x term and a z term, neither of which depends on the world seed
+func chunkTermX(x int32) int64 {
+	return int64(x*x*4987142) + int64(x*5947611)
+}
+func chunkTermZ(z int32) int64 {
+	return int64(z*z)*4392871 + // sic
+		int64(z*389711)
+}
+
+// Bedrock seeds a Mersenne Twister from the chunk coordinates
+func calcBedrockChunk(x, z int32) bool {
+	return mtFirst(uint32(x)*0x1f1f1f1f^uint32(z))%10 == 0
+}
@@ -3,7 +3,39 @@ package cpu
 import "testing"
 
 func TestZ23(t *testing.T) {
-	if World(1).CalcChunk(-1, 23) {
+	if JavaWorld(1).CalcChunk(-1, 23) {
 		t.Error("-1, 23 should not be a slime chunk")
 	}
 }
+
+// The slime chunks from -8, -8 up to 8, 8, found with C++'s std::mt19937
+var bedrockSlimeChunks = map[[2]int32]bool{
+	{7, -8}: true, {-6, -7}: true, {4, -6}: true, {0, -5}: true, {-8, -4}: true,
+	{6, -4}: true, {7, -3}: true, {-3, -2}: true, {0, -2}: true, {1, -2}: true,
+	{-8, -1}: true, {-4, -1}: true, {-3, -1}: true, {-6, 0}: true, {-1, 0}: true,
+	{3, 0}: true, {3, 1}: true, {-4, 2}: true, {-7, 3}: true, {-8, 4}: true,
+	{0, 4}: true, {6, 5}: true, {-7, 6}: true, {0, 6}: true, {4, 7}: true,
+}
+
+func TestBedrockChunk(t *testing.T) {
+	world := BedrockWorld()
+	for z := int32(-8); z < 8; z++ {
+		for x := int32(-8); x < 8; x++ {
+			if world.CalcChunk(x, z) != bedrockSlimeChunks[[2]int32{x, z}] {
+				t.Errorf("Incorrect Bedrock chunk at %d, %d", x, z)
+			}
+		}
+	}
+}
+
+func TestSectionComputeBedrock(t *testing.T) {
+	sec := NewSection(-70, 300, DefaultSectionSize)
+	sec.Compute(BedrockWorld())
+	for z := int32(0); z < sec.Size; z++ {
+		for x := int32(0); x < sec.Size; x++ {
+			if sec.Get(x, z) != BedrockWorld().CalcChunk(sec.X+x, sec.Z+z) {
+				t.Fatalf("Incorrect chunk at %d, %d", x, z)
+			}
+		}
+	}
+}
@@ -0,0 +1,242 @@
+package dist
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/vktec/slimy"
+)
+
+type tileState int
+
+const (
+	tilePending tileState = iota
+	tileIssued
+	tileDone
+)
+
+type coordTile struct {
+	Tile
+	state    tileState
+	deadline time.Time // When an issued tile is given to another worker
+}
+
+// Splits a search into tiles and hands them out to workers over HTTP.
+// A Coordinator is an http.Handler serving the protocol described in the package documentation.
+type Coordinator struct {
+	job      Job
+	timeout  time.Duration
+	order    slimy.Order
+	progress *slimy.ProgressReporter
+
+	// Called when a tile is issued or completed, if not nil
+	Log func(format string, args ...interface{})
+
+	mu        sync.Mutex
+	tiles     []coordTile
+	remaining int
+	results   []slimy.Result
+	top       *slimy.TopK
+	done      chan struct{}
+
+	mux *http.ServeMux
+}
+
+// Creates a coordinator for a search of every mask centre from x0, z0 up to but not including x1, z1, split into square tiles of the given size.
+// The job's ID is filled in if it is empty.
+// A tile that isn't completed within timeout of being issued is issued again to the next worker that asks.
+// If progress is not nil, it is called periodically with the number of chunks in completed tiles.
+func NewCoordinator(job Job, x0, z0, x1, z1, tileSize int32, timeout time.Duration, progress func(slimy.Progress)) *Coordinator {
+	if x0 > x1 {
+		x0, x1 = x1, x0
+	}
+	if z0 > z1 {
+		z0, z1 = z1, z0
+	}
+	if job.ID == "" {
+		var id [8]byte
+		rand.Read(id[:])
+		job.ID = hex.EncodeToString(id[:])
+	}
+
+	c := &Coordinator{
+		job:      job,
+		timeout:  timeout,
+		order:    job.Threshold.Order(),
+		progress: slimy.NewProgressReporter(progress, int64(x1-x0)*int64(z1-z0)),
+		done:     make(chan struct{}),
+		mux:      http.NewServeMux(),
+	}
+	if job.Limit > 0 {
+		c.top = slimy.NewTopK(job.Limit, c.order)
+	}
+	for x := x0; x < x1; x += tileSize {
+		for z := z0; z < z1; z += tileSize {
+			t := Tile{job.ID, len(c.tiles), x, z, min32(x+tileSize, x1), min32(z+tileSize, z1)}
+			c.tiles = append(c.tiles, coordTile{Tile: t})
+		}
+	}
+	c.remaining = len(c.tiles)
+	if c.remaining == 0 {
+		close(c.done)
+	}
+
+	c.mux.HandleFunc("/job", c.serveJob)
+	c.mux.HandleFunc("/claim", c.serveClaim)
+	c.mux.HandleFunc("/complete", c.serveComplete)
+	return c
+}
+
+func (c *Coordinator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.mux.ServeHTTP(w, r)
+}
+
+func (c *Coordinator) Job() Job {
+	return c.job
+}
+
+// Waits for every tile to be completed, then returns the results, best first.
+// If ctx is cancelled first, returns the results of the tiles completed so far, along with the context's error.
+func (c *Coordinator) Wait(ctx context.Context) ([]slimy.Result, error) {
+	var err error
+	select {
+	case <-c.done:
+		c.progress.Finish()
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.top != nil {
+		return c.top.Results(), err
+	}
+	results := append([]slimy.Result(nil), c.results...)
+	slimy.SortResults(results, c.order)
+	return results, err
+}
+
+func (c *Coordinator) logf(format string, args ...interface{}) {
+	if c.Log != nil {
+		c.Log(format, args...)
+	}
+}
+
+func (c *Coordinator) serveJob(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	writeJSON(w, c.job)
+}
+
+func (c *Coordinator) serveClaim(w http.ResponseWriter, r *http.Request) {
+	var claim Claim
+	if !readJSON(w, r, &claim) {
+		return
+	}
+
+	tile, ok := c.claim(time.Now())
+	switch {
+	case ok:
+		c.logf("Issued tile %d to %s", tile.Index, claim.Worker)
+		writeJSON(w, tile)
+	case c.finished():
+		w.WriteHeader(http.StatusGone)
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// Picks a tile that hasn't been issued, or one whose worker has timed out
+func (c *Coordinator) claim(now time.Time) (Tile, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i := range c.tiles {
+		t := &c.tiles[i]
+		if t.state == tilePending || t.state == tileIssued && now.After(t.deadline) {
+			t.state = tileIssued
+			t.deadline = now.Add(c.timeout)
+			return t.Tile, true
+		}
+	}
+	return Tile{}, false
+}
+
+func (c *Coordinator) finished() bool {
+	select {
+	case <-c.done:
+		return true
+	default:
+		return false
+	}
+}
+
+func (c *Coordinator) serveComplete(w http.ResponseWriter, r *http.Request) {
+	var comp Completion
+	if !readJSON(w, r, &comp) {
+		return
+	}
+	if comp.Tile.Job != c.job.ID {
+		http.Error(w, "Tile is from a different job", http.StatusConflict)
+		return
+	}
+	if comp.Tile.Index < 0 || comp.Tile.Index >= len(c.tiles) || comp.Tile != c.tiles[comp.Tile.Index].Tile {
+		http.Error(w, "No such tile", http.StatusBadRequest)
+		return
+	}
+
+	c.mu.Lock()
+	t := &c.tiles[comp.Tile.Index]
+	if t.state == tileDone {
+		// Another worker finished it first, after this one timed out
+		c.mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	t.state = tileDone
+	if c.top != nil {
+		c.top.Add(comp.Results...)
+	} else {
+		c.results = append(c.results, comp.Results...)
+	}
+	c.remaining--
+	if c.remaining == 0 {
+		close(c.done)
+	}
+	c.mu.Unlock()
+
+	c.logf("%s completed tile %d with %d results", comp.Worker, t.Index, len(comp.Results))
+	c.progress.Add(int64(t.X1-t.X0) * int64(t.Z1-t.Z0))
+	w.WriteHeader(http.StatusOK)
+}
+
+// Decodes a JSON request body, responding with an error if it can't
+func readJSON(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return false
+	}
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func min32(a, b int32) int32 {
+	if a < b {
+		return a
+	}
+	return b
+}
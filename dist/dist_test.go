@@ -0,0 +1,187 @@
+package dist
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/vktec/slimy"
+	"github.com/vktec/slimy/cpu"
+	"github.com/vktec/slimy/util"
+)
+
+func runDistributed(t *testing.T, job Job, x0, z0, x1, z1 int32, stall bool) []slimy.Result {
+	coord := NewCoordinator(job, x0, z0, x1, z1, 37, 200*time.Millisecond, nil)
+	server := httptest.NewServer(coord)
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if stall {
+		// A worker that claims a tile and is never heard from again
+		var tile Tile
+		w := &Worker{Name: "stalled", URL: server.URL}
+		if _, err := w.request(ctx, http.MethodPost, "/claim", Claim{w.Name}, &tile); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	errs := make(chan error, 3)
+	wgroup := new(sync.WaitGroup)
+	for i := 0; i < 3; i++ {
+		wgroup.Add(1)
+		go func(i int) {
+			defer wgroup.Done()
+			w := &Worker{Name: fmt.Sprint("worker", i), URL: server.URL, PollInterval: 20 * time.Millisecond}
+			job, err := w.Job(ctx)
+			if err != nil {
+				errs <- err
+				return
+			}
+			mask, err := job.MaskImage()
+			if err != nil {
+				errs <- err
+				return
+			}
+			s, err := cpu.NewSearcher(1, mask, job.Edition, job.Weighted)
+			if err != nil {
+				errs <- err
+				return
+			}
+			errs <- w.Run(ctx, job, s)
+		}(i)
+	}
+
+	results, err := coord.Wait(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wgroup.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return results
+}
+
+func checkResults(t *testing.T, got, expected []slimy.Result) {
+	if len(got) != len(expected) {
+		t.Fatalf("Wrong number of results: expected %d, got %d", len(expected), len(got))
+	}
+	for i := range got {
+		if got[i] != expected[i] {
+			t.Fatalf("Incorrect result at index %d: expected %v, got %v", i, expected[i], got[i])
+		}
+	}
+}
+
+func TestDistributedSearch(t *testing.T) {
+	maskImg := util.GenDonut(1, 4)
+	mask, err := EncodeMask(maskImg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []struct {
+		limit int
+		stall bool
+	}{{0, false}, {10, false}, {0, true}} {
+		job := Job{Seed: 12, Threshold: slimy.AtLeast(8), Mask: mask, Limit: c.limit}
+		s, _ := cpu.NewSearcher(2, maskImg, slimy.JavaEdition, false)
+		expected, _ := s.SearchContext(context.Background(), -100, -60, 150, 170, job.Threshold, job.Seed, slimy.Options{Limit: c.limit})
+		checkResults(t, runDistributed(t, job, -100, -60, 150, 170, c.stall), expected)
+	}
+}
+
+func TestCoordinatorReissue(t *testing.T) {
+	coord := NewCoordinator(Job{}, 0, 0, 20, 10, 10, time.Minute, nil)
+	now := time.Now()
+	a, _ := coord.claim(now)
+	b, _ := coord.claim(now)
+	if a.Index == b.Index {
+		t.Fatal("Same tile issued twice")
+	}
+	if _, ok := coord.claim(now); ok {
+		t.Fatal("Issued a tile when all were taken")
+	}
+	c, ok := coord.claim(now.Add(2 * time.Minute))
+	if !ok || c.Index != a.Index {
+		t.Fatalf("Expected timed out tile %d to be reissued, got %v", a.Index, c)
+	}
+}
+
+func TestWorkerRetry(t *testing.T) {
+	maskImg := util.GenDonut(1, 4)
+	mask, err := EncodeMask(maskImg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	job := Job{Seed: 12, Threshold: slimy.AtLeast(8), Mask: mask}
+	coord := NewCoordinator(job, -50, -50, 50, 50, 37, time.Minute, nil)
+
+	// Every other claim and completion fails, alternating between a server error and a dropped connection
+	var mu sync.Mutex
+	failures := 0
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests++
+		fail := r.URL.Path != "/job" && requests%2 == 0
+		if fail {
+			failures++
+		}
+		drop := failures%2 == 0
+		mu.Unlock()
+		switch {
+		case !fail:
+			coord.ServeHTTP(w, r)
+		case drop:
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			conn.Close()
+		default:
+			http.Error(w, "Try again later", http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	s, _ := cpu.NewSearcher(1, maskImg, slimy.JavaEdition, false)
+	w := &Worker{Name: "flaky", URL: server.URL, RetryDelay: time.Millisecond}
+	if err := w.Run(ctx, coord.Job(), s); err != nil {
+		t.Fatal(err)
+	}
+	if failures < 4 {
+		t.Errorf("Expected several failed requests, got %d", failures)
+	}
+
+	results, err := coord.Wait(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, _ := s.SearchContext(context.Background(), -50, -50, 50, 50, job.Threshold, job.Seed, slimy.Options{})
+	checkResults(t, results, expected)
+}
+
+func TestWorkerNoRetry(t *testing.T) {
+	coord := NewCoordinator(Job{}, 0, 0, 10, 10, 10, time.Minute, nil)
+	server := httptest.NewServer(coord)
+	defer server.Close()
+
+	// The coordinator rejects work for a different job, which retrying can't fix
+	w := &Worker{Name: "lost", URL: server.URL, RetryDelay: time.Millisecond}
+	_, err := w.retryRequest(context.Background(), http.MethodPost, "/complete", Completion{w.Name, Tile{Job: "other"}, nil}, nil)
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+	if _, ok := err.(retryableError); ok {
+		t.Errorf("Expected a permanent error, got %v", err)
+	}
+}
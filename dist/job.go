@@ -0,0 +1,59 @@
+// Package dist splits searches between machines.
+// A Coordinator hands out tiles of the search area over HTTP, and workers on any machine search them with whichever Searcher they have.
+//
+// The protocol is JSON over HTTP:
+//
+//	GET  /job       returns the Job being searched
+//	POST /claim     claims a Tile to search, given a Claim. Responds with 204 No Content if every tile is taken, or 410 Gone once the job is finished
+//	POST /complete  submits the Completion of a tile
+package dist
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+
+	"github.com/vktec/slimy"
+)
+
+// Everything a worker needs to know to search tiles of a job
+type Job struct {
+	ID        string // Identifies this job, so that work for a different job is rejected
+	Seed      int64
+	Edition   slimy.Edition
+	Threshold slimy.Threshold
+	Mask      []byte // PNG encoded mask image
+	Weighted  bool   // Whether the mask is weighted, as with cpu.NewWeightedMask
+	Limit     int    // If positive, only the best Limit results are kept
+}
+
+// Encodes a mask image for a Job
+func EncodeMask(img image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	err := png.Encode(&buf, img)
+	return buf.Bytes(), err
+}
+
+// Decodes the job's mask image
+func (j Job) MaskImage() (image.Image, error) {
+	return png.Decode(bytes.NewReader(j.Mask))
+}
+
+// An area for a worker to search, covering mask centres from X0, Z0 up to but not including X1, Z1
+type Tile struct {
+	Job            string
+	Index          int
+	X0, Z0, X1, Z1 int32
+}
+
+// A request for a tile
+type Claim struct {
+	Worker string // Identifies the worker, for logging
+}
+
+// The results of searching a tile
+type Completion struct {
+	Worker  string
+	Tile    Tile
+	Results []slimy.Result
+}
@@ -0,0 +1,170 @@
+package dist
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/vktec/slimy"
+)
+
+// How long a worker waits before asking again when every tile is taken, by default
+const DefaultPollInterval = 2 * time.Second
+
+// The longest a worker waits before retrying a failed request
+const maxRetryDelay = time.Minute
+
+// Searches tiles for a coordinator
+type Worker struct {
+	Name   string       // Sent to the coordinator, for logging
+	Client *http.Client // Defaults to http.DefaultClient
+	URL    string       // The coordinator's base URL
+
+	// How long to wait before asking again when every tile is taken. Defaults to DefaultPollInterval
+	PollInterval time.Duration
+
+	// How long to wait before retrying a claim or completion that failed, doubling with each further failure up to a minute.
+	// Defaults to DefaultPollInterval
+	RetryDelay time.Duration
+
+	// Called with the progress of each tile, if not nil
+	Progress func(Tile, slimy.Progress)
+
+	// Called when a request fails and will be retried, if not nil
+	Log func(format string, args ...interface{})
+}
+
+// Fetches the coordinator's job
+func (w *Worker) Job(ctx context.Context) (job Job, err error) {
+	_, err = w.request(ctx, http.MethodGet, "/job", nil, &job)
+	return job, err
+}
+
+// Searches tiles with s until the coordinator's job is finished, or ctx is cancelled.
+// The searcher must have been created for the job's mask and edition.
+// Claims and completions that fail because of the network or a server error are retried until ctx is cancelled, so the coordinator can be restarted or briefly unreachable.
+func (w *Worker) Run(ctx context.Context, job Job, s slimy.Searcher) error {
+	for {
+		var tile Tile
+		status, err := w.retryRequest(ctx, http.MethodPost, "/claim", Claim{w.Name}, &tile)
+		if err != nil {
+			return err
+		}
+		switch status {
+		case http.StatusGone:
+			return nil
+		case http.StatusNoContent:
+			// Every tile is taken, but some may time out
+			poll := w.PollInterval
+			if poll <= 0 {
+				poll = DefaultPollInterval
+			}
+			select {
+			case <-time.After(poll):
+				continue
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		if tile.Job != job.ID {
+			return fmt.Errorf("Coordinator is running a different job")
+		}
+
+		opts := slimy.Options{Limit: job.Limit}
+		if w.Progress != nil {
+			opts.Progress = func(p slimy.Progress) { w.Progress(tile, p) }
+		}
+		results, err := s.SearchContext(ctx, tile.X0, tile.Z0, tile.X1, tile.Z1, job.Threshold, job.Seed, opts)
+		if err != nil {
+			return err
+		}
+		if _, err := w.retryRequest(ctx, http.MethodPost, "/complete", Completion{w.Name, tile, results}, nil); err != nil {
+			return err
+		}
+	}
+}
+
+// An error that may not happen again if the request is repeated
+type retryableError struct{ error }
+
+// Sends a request like request, retrying with increasing delays until it succeeds, fails with an error that can't be retried, or ctx is cancelled
+func (w *Worker) retryRequest(ctx context.Context, method, path string, body, resp interface{}) (status int, err error) {
+	delay := w.RetryDelay
+	if delay <= 0 {
+		delay = DefaultPollInterval
+	}
+	for {
+		status, err = w.request(ctx, method, path, body, resp)
+		if _, ok := err.(retryableError); !ok {
+			return status, err
+		}
+		if ctx.Err() != nil {
+			return status, ctx.Err()
+		}
+		if w.Log != nil {
+			w.Log("%s %s failed, retrying in %s: %s", method, path, delay, err)
+		}
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return status, ctx.Err()
+		}
+		if delay *= 2; delay > maxRetryDelay {
+			delay = maxRetryDelay
+		}
+	}
+}
+
+// Sends a request to the coordinator, decoding the response into resp if it has content
+func (w *Worker) request(ctx context.Context, method, path string, body, resp interface{}) (status int, err error) {
+	var reqBody io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return 0, err
+		}
+		reqBody = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, strings.TrimSuffix(w.URL, "/")+path, reqBody)
+	if err != nil {
+		return 0, err
+	}
+	req = req.WithContext(ctx)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	client := w.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return 0, retryableError{err}
+	}
+	defer res.Body.Close()
+
+	switch res.StatusCode {
+	case http.StatusOK:
+		if resp != nil {
+			if err = json.NewDecoder(res.Body).Decode(resp); err != nil {
+				// The response was most likely cut off
+				err = retryableError{err}
+			}
+		}
+	case http.StatusNoContent, http.StatusGone:
+	default:
+		msg, _ := ioutil.ReadAll(io.LimitReader(res.Body, 1024))
+		err = fmt.Errorf("Coordinator responded with %s: %s", res.Status, strings.TrimSpace(string(msg)))
+		if res.StatusCode >= 500 || res.StatusCode == http.StatusTooManyRequests || res.StatusCode == http.StatusRequestTimeout {
+			err = retryableError{err}
+		}
+	}
+	return res.StatusCode, err
+}
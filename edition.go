@@ -0,0 +1,34 @@
+package slimy
+
+import "fmt"
+
+// The edition of Minecraft a world is from, which decides where its slime chunks are
+type Edition int
+
+const (
+	JavaEdition    Edition = iota // Slime chunks depend on the world seed
+	BedrockEdition                // Slime chunks are the same in every world
+)
+
+func (e Edition) String() string {
+	switch e {
+	case JavaEdition:
+		return "java"
+	case BedrockEdition:
+		return "bedrock"
+	default:
+		return fmt.Sprintf("Edition(%d)", int(e))
+	}
+}
+
+// Parses an edition name, as returned by Edition.String
+func ParseEdition(s string) (Edition, error) {
+	switch s {
+	case "java":
+		return JavaEdition, nil
+	case "bedrock":
+		return BedrockEdition, nil
+	default:
+		return 0, fmt.Errorf("Unknown edition %q (valid options: java, bedrock)", s)
+	}
+}
@@ -5,6 +5,8 @@ import (
 
 	"github.com/vktec/gll"
 	"github.com/vktec/gll/glh"
+	"github.com/vktec/slimy"
+	"github.com/vktec/slimy/util"
 )
 
 func BuildShader(gl gll.GL330, vert, frag string) (prog uint32, err error) {
@@ -26,7 +28,9 @@ func BuildComputeShader(gl gll.GL420, source ...string) (prog uint32, err error)
 	return glh.NewProgram(gl, shad)
 }
 
-func UploadMask(gl gll.GL330, img image.Image) (tex uint32) {
+// Uploads a mask image to a single-channel texture.
+// Weighted masks store each cell's weight, otherwise cells are either 0 or 1.
+func UploadMask(gl gll.GL330, img image.Image, weighted bool) (tex uint32) {
 	gl.GenTextures(1, &tex)
 	gl.BindTexture(gll.TEXTURE_RECTANGLE, tex)
 	gl.TexParameteri(gll.TEXTURE_RECTANGLE, gll.TEXTURE_WRAP_S, gll.CLAMP_TO_BORDER)
@@ -37,11 +41,12 @@ func UploadMask(gl gll.GL330, img image.Image) (tex uint32) {
 	data := make([][4]uint8, dim.Dx()*dim.Dy())
 	for y := dim.Min.Y; y < dim.Max.Y; y++ {
 		for x := dim.Min.X; x < dim.Max.X; x++ {
-			r, g, b, a := img.At(x, y).RGBA()
-			if (r > 0x7fff || g > 0x7fff || b > 0x7fff) && a > 0x7fff {
-				tx := x - dim.Min.X
-				ty := y - dim.Min.Y
-				data[ty*dim.Dx()+tx][0] = 0xff
+			tx := x - dim.Min.X
+			ty := y - dim.Min.Y
+			if weighted {
+				data[ty*dim.Dx()+tx][0] = util.MaskWeight(img.At(x, y))
+			} else if util.InMask(img.At(x, y)) {
+				data[ty*dim.Dx()+tx][0] = util.MaxMaskWeight
 			}
 		}
 	}
@@ -62,3 +67,12 @@ func ExtensionSupported(gl gll.GL300, name string) bool {
 	}
 	return false
 }
+
+// Sets the bedrock uniform used by IsSlime
+func SetEdition(gl gll.GL330, loc int32, edition slimy.Edition) {
+	bedrock := int32(0)
+	if edition == slimy.BedrockEdition {
+		bedrock = 1
+	}
+	gl.Uniform1i(loc, bedrock)
+}
@@ -1,15 +1,19 @@
 package gpu
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"image"
+	"math"
+	"time"
 	"unsafe"
 
 	"github.com/go-gl/glfw/v3.3/glfw"
 	"github.com/vktec/gldebug"
 	"github.com/vktec/gll"
 	"github.com/vktec/slimy"
+	"github.com/vktec/slimy/util"
 )
 
 type Searcher struct {
@@ -23,6 +27,8 @@ type Searcher struct {
 
 	useInt64     bool
 	useGroupSize bool
+	edition      slimy.Edition
+	weighted     bool
 
 	prog      uint32
 	maskTex   uint32
@@ -30,10 +36,10 @@ type Searcher struct {
 	countBuf  uint32
 	resultBuf uint32
 
-	uOffset, uThreshold, uWorldSeed, uWorldSeedV int32
+	uOffset, uThreshold, uWorldSeed, uWorldSeedV, uBedrock int32
 }
 
-func NewGLFWSearcher(mask image.Image) (*Searcher, error) {
+func NewGLFWSearcher(mask image.Image, edition slimy.Edition, weighted bool) (*Searcher, error) {
 	if err := glfw.Init(); err != nil {
 		return nil, err
 	}
@@ -48,7 +54,7 @@ func NewGLFWSearcher(mask image.Image) (*Searcher, error) {
 	if err != nil {
 		return nil, err
 	}
-	s := &Searcher{ctx: win, getProcAddr: glfw.GetProcAddress}
+	s := &Searcher{ctx: win, getProcAddr: glfw.GetProcAddress, edition: edition, weighted: weighted}
 	if err := s.init(mask); err != nil {
 		s.Destroy()
 		return nil, err
@@ -73,7 +79,7 @@ func (s *Searcher) init(mask image.Image) (err error) {
 	s.useGroupSize = ExtensionSupported(s, "GL_ARB_compute_variable_group_size")
 
 	s.maskDim = mask.Bounds().Canon().Size()
-	s.maskTex = UploadMask(s, mask)
+	s.maskTex = UploadMask(s, mask, s.weighted)
 
 	// TODO: try out other usage combinations including STREAM, DRAW and READ
 	s.GenBuffers(1, &s.countBuf)
@@ -124,6 +130,7 @@ func (s *Searcher) initProg() (err error) {
 	s.uThreshold = s.GetUniformLocation(s.prog, gll.Str("threshold\000"))
 	s.uWorldSeed = s.GetUniformLocation(s.prog, gll.Str("worldSeed\000"))
 	s.uWorldSeedV = s.GetUniformLocation(s.prog, gll.Str("worldSeedV\000"))
+	s.uBedrock = s.GetUniformLocation(s.prog, gll.Str("bedrock\000"))
 
 	return nil
 }
@@ -133,9 +140,15 @@ const (
 	resultBufferLength = searchRegionWidth * searchRegionWidth
 )
 
-func (s *Searcher) Search(x0, z0, x1, z1 int32, threshold int, worldSeed int64) []slimy.Result {
+func (s *Searcher) Search(x0, z0, x1, z1 int32, threshold slimy.Threshold, worldSeed int64) []slimy.Result {
+	results, _ := s.SearchContext(context.Background(), x0, z0, x1, z1, threshold, worldSeed, slimy.Options{})
+	return results
+}
+
+func (s *Searcher) SearchContext(ctx context.Context, x0, z0, x1, z1 int32, threshold slimy.Threshold, worldSeed int64, opts slimy.Options) ([]slimy.Result, error) {
 	// TODO: search asynchronously or on a different thread so we don't block rendering
 	// TODO: split large searches into multiple batches
+	progress := slimy.NewProgressReporter(opts.Progress, int64(x1-x0)*int64(z1-z0))
 
 	// Adjust search region so we scan all centres within the box rather than corners
 	x0 -= int32(s.maskDim.X / 2)
@@ -146,11 +159,14 @@ func (s *Searcher) Search(x0, z0, x1, z1 int32, threshold int, worldSeed int64)
 	s.activate()
 	s.initProg()
 	s.UseProgram(s.prog)
-	s.Uniform1i(s.uThreshold, int32(threshold))
+	tmin, tmax := threshold.Bounds()
+	// The shader compares the sum of the integer weights
+	s.Uniform2i(s.uThreshold, clampInt32(uint64(tmin)*util.MaxMaskWeight), clampInt32(uint64(tmax)*util.MaxMaskWeight))
 	if s.useInt64 {
 		s.Uniform1i64ARB(s.uWorldSeed, worldSeed)
 	}
 	s.Uniform2ui(s.uWorldSeedV, uint32(worldSeed>>32), uint32(worldSeed))
+	SetEdition(s, s.uBedrock, s.edition)
 
 	s.ActiveTexture(gll.TEXTURE0)
 	s.BindTexture(gll.TEXTURE_RECTANGLE, s.maskTex)
@@ -167,50 +183,90 @@ func (s *Searcher) Search(x0, z0, x1, z1 int32, threshold int, worldSeed int64)
 	returnC := make(chan []slimy.Result)
 	go func() {
 		var results []slimy.Result
+		var top *slimy.TopK
+		if opts.Limit > 0 && opts.Stream == nil {
+			top = slimy.NewTopK(opts.Limit, threshold.Order())
+		}
 		for group := range resultC {
-			for _, res := range group {
-				i := len(results)
-				results = append(results, res)
-				// TODO: use a faster sorting alg
-				for i > 0 && res.OrderBefore(results[i-1], threshold) {
-					results[i] = results[i-1]
-					i--
+			switch {
+			case opts.Stream != nil:
+				for _, res := range group {
+					opts.Stream(res)
 				}
-				results[i] = res
+			case top != nil:
+				top.Add(group...)
+			default:
+				results = append(results, group...)
 			}
 		}
+
+		if top != nil {
+			results = top.Results()
+		} else {
+			slimy.SortResults(results, threshold.Order())
+		}
 		returnC <- results
 	}()
 
-	rw, rh := (x1-x0)/searchRegionWidth, (z1-z0)/searchRegionWidth
-	for rz := int32(0); rz < rh; rz++ {
-		rz0 := z0 + rz*searchRegionWidth
-		rz1 := rz0 + searchRegionWidth
-		for rx := int32(0); rx < rw; rx++ {
-			rx0 := x0 + rx*searchRegionWidth
-			rx1 := rx0 + searchRegionWidth
+	// Partition the search into regions, checking for cancellation between each one
+	var err error
+regions:
+	for rz0 := z0; rz0 < z1; rz0 += searchRegionWidth {
+		rz1 := min32(rz0+searchRegionWidth, z1)
+		for rx0 := x0; rx0 < x1; rx0 += searchRegionWidth {
+			if err = ctx.Err(); err != nil {
+				break regions
+			}
+			rx1 := min32(rx0+searchRegionWidth, x1)
 			resultC <- s.executeSearch(rx0, rz0, rx1, rz1)
-		}
-		if rw*searchRegionWidth < x1-x0 {
-			rx0 := x0 + rw*searchRegionWidth
-			resultC <- s.executeSearch(rx0, rz0, x1, rz1)
+			progress.Add(int64(rx1-rx0) * int64(rz1-rz0))
 		}
 	}
-	if rh*searchRegionWidth < z1-z0 {
-		rz0 := z0 + rh*searchRegionWidth
-		for rx := int32(0); rx < rw; rx++ {
-			rx0 := x0 + rx*searchRegionWidth
-			rx1 := rx0 + searchRegionWidth
-			resultC <- s.executeSearch(rx0, rz0, rx1, z1)
+
+	close(resultC)
+	results := <-returnC
+	progress.Finish()
+	return results, err
+}
+
+// Searches each seed in turn. Options.Stream is not supported, and Options.Limit applies to each seed separately.
+func (s *Searcher) SearchSeeds(ctx context.Context, x0, z0, x1, z1 int32, threshold slimy.Threshold, worldSeeds []int64, opts slimy.Options) ([]slimy.SeedResults, error) {
+	area := int64(x1-x0) * int64(z1-z0)
+	total := area * int64(len(worldSeeds))
+	start := time.Now()
+	seedOpts := slimy.Options{Limit: opts.Limit}
+
+	var results []slimy.SeedResults
+	for i, seed := range worldSeeds {
+		if opts.Progress != nil {
+			// Report progress across the whole batch rather than for each seed
+			done := area * int64(i)
+			seedOpts.Progress = func(p slimy.Progress) {
+				opts.Progress(slimy.Progress{Scanned: done + p.Scanned, Total: total, Elapsed: time.Since(start)})
+			}
 		}
-		if rw*searchRegionWidth < x1-x0 {
-			rx0 := x0 + rw*searchRegionWidth
-			resultC <- s.executeSearch(rx0, rz0, x1, z1)
+
+		seedResults, err := s.SearchContext(ctx, x0, z0, x1, z1, threshold, seed, seedOpts)
+		results = append(results, slimy.SeedResults{Seed: seed, Results: seedResults})
+		if err != nil {
+			return results, err
 		}
 	}
+	return results, nil
+}
 
-	close(resultC)
-	return <-returnC
+func clampInt32(n uint64) int32 {
+	if n > math.MaxInt32 {
+		return math.MaxInt32
+	}
+	return int32(n)
+}
+
+func min32(a, b int32) int32 {
+	if a < b {
+		return a
+	}
+	return b
 }
 
 func (s *Searcher) executeSearch(x0, z0, x1, z1 int32) []slimy.Result {
@@ -237,9 +293,10 @@ func (s *Searcher) executeSearch(x0, z0, x1, z1 int32) []slimy.Result {
 		centerOffX, centerOffZ := int32(s.maskDim.X/2), int32(s.maskDim.Y/2)
 		for i, gpuRes := range gpuResults {
 			results[i] = slimy.Result{
-				x0 + int32(gpuRes.xoff) + centerOffX,
-				z0 + int32(gpuRes.zoff) + centerOffZ,
-				uint(gpuRes.count),
+				X:     x0 + int32(gpuRes.xoff) + centerOffX,
+				Z:     z0 + int32(gpuRes.zoff) + centerOffZ,
+				Count: uint(gpuRes.count),
+				Score: float64(gpuRes.weight) / util.MaxMaskWeight,
 			}
 		}
 		return results
@@ -249,5 +306,5 @@ func (s *Searcher) executeSearch(x0, z0, x1, z1 int32) []slimy.Result {
 }
 
 type gpuResult struct {
-	xoff, zoff, count, _ uint32
+	xoff, zoff, count, weight uint32
 }
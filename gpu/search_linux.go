@@ -4,17 +4,18 @@ import (
 	"image"
 
 	"github.com/vktec/glhl"
+	"github.com/vktec/slimy"
 )
 
-func NewSearcher(mask image.Image) (*Searcher, error) {
+func NewSearcher(mask image.Image, edition slimy.Edition, weighted bool) (*Searcher, error) {
 	flags := glhl.Core
 	if Debug {
 		flags |= glhl.Debug
 	}
 	ctx, err := glhl.NewContext(4, 2, flags)
 	if err != nil {
-		return NewGLFWSearcher(mask)
+		return NewGLFWSearcher(mask, edition, weighted)
 	}
-	s := &Searcher{ctx: ctx, getProcAddr: glhl.GetProcAddr}
+	s := &Searcher{ctx: ctx, getProcAddr: glhl.GetProcAddr, edition: edition, weighted: weighted}
 	return s, s.init(mask)
 }
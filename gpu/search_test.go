@@ -0,0 +1,66 @@
+package gpu
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"math"
+	"sort"
+	"testing"
+
+	"github.com/vktec/slimy"
+	"github.com/vktec/slimy/cpu"
+)
+
+// The GPU sums whole weights just like the CPU, so only the final division can differ
+const scoreTolerance = 1e-9
+
+// A mask whose weights fall off from the centre
+func weightedMask() image.Image {
+	img := image.NewGray(image.Rect(0, 0, 9, 9))
+	for y := 0; y < 9; y++ {
+		for x := 0; x < 9; x++ {
+			d := math.Hypot(float64(x-4), float64(y-4))
+			if d <= 4 {
+				img.SetGray(x, y, color.Gray{uint8(255 - 50*d)})
+			}
+		}
+	}
+	return img
+}
+
+func TestSearchWeightedMatchesCPU(t *testing.T) {
+	mask := weightedMask()
+	gs, err := NewSearcher(mask, slimy.JavaEdition, true)
+	if err != nil {
+		t.Skip("GPU search unavailable:", err)
+	}
+	defer gs.Destroy()
+	cs, err := cpu.NewSearcher(0, mask, slimy.JavaEdition, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	threshold := slimy.AtLeast(5)
+	got, err := gs.SearchContext(context.Background(), -200, -200, 200, 200, threshold, 42, slimy.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, _ := cs.SearchContext(context.Background(), -200, -200, 200, 200, threshold, 42, slimy.Options{})
+
+	// Equal scores may be ordered differently, so compare by position
+	byPos := func(r []slimy.Result) func(i, j int) bool {
+		return func(i, j int) bool { return r[i].Z < r[j].Z || r[i].Z == r[j].Z && r[i].X < r[j].X }
+	}
+	sort.Slice(got, byPos(got))
+	sort.Slice(expected, byPos(expected))
+	if len(got) != len(expected) {
+		t.Fatalf("Wrong number of results: expected %d, got %d", len(expected), len(got))
+	}
+	for i := range got {
+		g, e := got[i], expected[i]
+		if g.X != e.X || g.Z != e.Z || g.Count != e.Count || math.Abs(g.Score-e.Score) > scoreTolerance {
+			t.Fatalf("Incorrect result at index %d: expected %v, got %v", i, e, g)
+		}
+	}
+}
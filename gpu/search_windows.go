@@ -1,7 +1,11 @@
 package gpu
 
-import "image"
+import (
+	"image"
 
-func NewSearcher(mask image.Image) (*Searcher, error) {
-	return NewGLFWSearcher(mask)
+	"github.com/vktec/slimy"
+)
+
+func NewSearcher(mask image.Image, edition slimy.Edition, weighted bool) (*Searcher, error) {
+	return NewGLFWSearcher(mask, edition, weighted)
 }
@@ -19,7 +19,7 @@ layout(local_size_x = %d, local_size_y = %d) in;
 
 const searchComp = `
 uniform ivec2 offset;
-uniform int threshold;
+uniform ivec2 threshold; // Inclusive min and max weight, where a full weight cell is 255
 layout(binding = 0) uniform sampler2DRect mask;
 layout(binding = 0) uniform atomic_uint resultCount;
 layout(std140, binding = 1) buffer resultData {
@@ -29,33 +29,32 @@ layout(std140, binding = 1) buffer resultData {
 ` + IsSlime + `
 #line 21
 shared int count;
-bool checkThreshold(int threshold, int count) {
-	if (threshold < 0) {
-		return count <= -threshold;
-	} else {
-		return count >= threshold;
-	}
+shared int weight;
+bool checkThreshold(ivec2 threshold, int count) {
+	return threshold.x <= count && count <= threshold.y;
 }
 void main() {
 	if (gl_LocalInvocationIndex == 0) {
 		count = 0;
+		weight = 0;
 	}
 	memoryBarrierShared();
 	barrier();
 
 	ivec2 coord = ivec2(gl_WorkGroupID.xy + gl_LocalInvocationID.xy) + offset;
 	bool slime = isSlime(coord);
-	bool mask = texelFetch(mask, ivec2(gl_LocalInvocationID.xy)).r >= 0.5;
+	int cellWeight = int(round(texelFetch(mask, ivec2(gl_LocalInvocationID.xy)).r * 255.0));
 
-	atomicAdd(count, int(slime) * int(mask));
+	atomicAdd(count, int(slime) * int(cellWeight > 0));
+	atomicAdd(weight, int(slime) * cellWeight);
 	memoryBarrierShared();
 	barrier();
 
 	if (gl_LocalInvocationIndex == 0) {
-		if (checkThreshold(threshold, count)) {
+		if (checkThreshold(threshold, weight)) {
 			uint idx = atomicCounterIncrement(resultCount);
 			memoryBarrierAtomicCounter();
-			results[idx] = ivec4(gl_WorkGroupID.xy, count, 0);
+			results[idx] = ivec4(gl_WorkGroupID.xy, count, weight);
 		}
 	}
 }
@@ -77,7 +76,7 @@ uniform int64_t worldSeed;
 uint64_t slime_magic = 0x5DEECE66Dul;
 uint64_t slime_mask = (1l << 48) - 1;
 
-bool isSlime(ivec2 c) {
+bool isSlimeJava(ivec2 c) {
 	// Calculate slime seed
 	uint64_t seed = worldSeed +
 		uint64_t(c.x*c.x*4987142) +
@@ -124,7 +123,7 @@ uniform uvec2 worldSeedV;
 uvec2 slimev_magic = uvec2(0x5, 0xDEECE66D);
 uvec2 slimev_mask = uvec2(0xffff, -1);
 
-bool isSlime(ivec2 c) {
+bool isSlimeJava(ivec2 c) {
 	// Calculate slime seed
 	uvec2 seed = worldSeedV;
 	seed = add64(seed, i64(c.x*c.x*4987142));
@@ -145,4 +144,28 @@ bool isSlime(ivec2 c) {
 	return val == 0;
 }
 #endif
+
+// Bedrock Edition seeds a Mersenne Twister from the chunk coordinates.
+// Only the first output is needed, which depends on state words 0, 1 and 397.
+bool isSlimeBedrock(ivec2 c) {
+	uint seed = uint(c.x)*0x1f1f1f1fu ^ uint(c.y);
+	uint s = seed, s1;
+	for (uint i = 1u; i <= 397u; i++) {
+		s = 1812433253u*(s ^ (s >> 30)) + i;
+		if (i == 1u) s1 = s;
+	}
+	uint y = (seed & 0x80000000u) | (s1 & 0x7fffffffu);
+	y = s ^ (y >> 1) ^ ((y & 1u) * 0x9908b0dfu);
+	// Temper
+	y ^= y >> 11;
+	y ^= (y << 7) & 0x9d2c5680u;
+	y ^= (y << 15) & 0xefc60000u;
+	y ^= y >> 18;
+	return y % 10u == 0u;
+}
+
+uniform bool bedrock;
+bool isSlime(ivec2 c) {
+	return bedrock ? isSlimeBedrock(c) : isSlimeJava(c);
+}
 `
@@ -0,0 +1,263 @@
+// Package nbt reads Minecraft's Named Binary Tag format, as used by level.dat files.
+//
+// Tags are decoded into Go values:
+//
+//	Byte       int8
+//	Short      int16
+//	Int        int32
+//	Long       int64
+//	Float      float32
+//	Double     float64
+//	Byte_Array []byte
+//	String     string
+//	List       []interface{}
+//	Compound   map[string]interface{}
+//	Int_Array  []int32
+//	Long_Array []int64
+package nbt
+
+import (
+	"bufio"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"unicode/utf16"
+)
+
+// Tag types
+const (
+	TagEnd byte = iota
+	TagByte
+	TagShort
+	TagInt
+	TagLong
+	TagFloat
+	TagDouble
+	TagByteArray
+	TagString
+	TagList
+	TagCompound
+	TagIntArray
+	TagLongArray
+)
+
+// The largest decompressed file Read accepts
+const MaxSize = 64 << 20
+
+// How deeply lists and compounds may be nested, the same as the game allows
+const MaxDepth = 512
+
+// Reads an NBT file, which may be gzip or zlib compressed, and returns the name and contents of its root compound
+func Read(r io.Reader) (name string, root map[string]interface{}, err error) {
+	br := bufio.NewReader(r)
+	magic, _ := br.Peek(2)
+	switch {
+	case len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b:
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return "", nil, err
+		}
+		defer gz.Close()
+		r = gz
+	case len(magic) == 2 && magic[0] == 0x78:
+		zr, err := zlib.NewReader(br)
+		if err != nil {
+			return "", nil, err
+		}
+		defer zr.Close()
+		r = zr
+	default:
+		r = br
+	}
+
+	data, err := ioutil.ReadAll(io.LimitReader(r, MaxSize+1))
+	if err != nil {
+		return "", nil, err
+	}
+	if len(data) > MaxSize {
+		return "", nil, errors.New("NBT file is too large")
+	}
+	return Decode(data)
+}
+
+// Decodes uncompressed NBT data, returning the name and contents of its root compound
+func Decode(data []byte) (name string, root map[string]interface{}, err error) {
+	d := &decoder{data: data}
+	// Errors are reported by panicking with a decodeError, to keep the decoder simple
+	defer func() {
+		if r := recover(); r != nil {
+			de, ok := r.(decodeError)
+			if !ok {
+				panic(r)
+			}
+			err = fmt.Errorf("Invalid NBT at offset %d: %s", d.pos, string(de))
+		}
+	}()
+
+	if typ := d.byte(); typ != TagCompound {
+		d.fail("root tag is type %d, not a compound", typ)
+	}
+	name = d.string()
+	root = d.compound(0)
+	return name, root, nil
+}
+
+type decodeError string
+
+type decoder struct {
+	data []byte
+	pos  int
+}
+
+func (d *decoder) fail(format string, args ...interface{}) {
+	panic(decodeError(fmt.Sprintf(format, args...)))
+}
+
+// Returns the next n bytes
+func (d *decoder) next(n int) []byte {
+	if n < 0 || n > len(d.data)-d.pos {
+		d.fail("unexpected end of data")
+	}
+	b := d.data[d.pos : d.pos+n]
+	d.pos += n
+	return b
+}
+
+func (d *decoder) byte() byte {
+	return d.next(1)[0]
+}
+
+func (d *decoder) uint16() uint16 {
+	return binary.BigEndian.Uint16(d.next(2))
+}
+
+func (d *decoder) uint32() uint32 {
+	return binary.BigEndian.Uint32(d.next(4))
+}
+
+func (d *decoder) uint64() uint64 {
+	return binary.BigEndian.Uint64(d.next(8))
+}
+
+// Reads the length of an array or list, checking that there is room for that many elements of the given size
+func (d *decoder) length(size int) int {
+	n := int(int32(d.uint32()))
+	if n < 0 {
+		d.fail("negative length %d", n)
+	}
+	if n > (len(d.data)-d.pos)/size {
+		d.fail("length %d is longer than the data", n)
+	}
+	return n
+}
+
+func (d *decoder) string() string {
+	return decodeModifiedUTF8(d.next(int(d.uint16())))
+}
+
+func (d *decoder) compound(depth int) map[string]interface{} {
+	m := make(map[string]interface{})
+	for {
+		typ := d.byte()
+		if typ == TagEnd {
+			return m
+		}
+		name := d.string()
+		m[name] = d.payload(typ, depth+1)
+	}
+}
+
+func (d *decoder) payload(typ byte, depth int) interface{} {
+	if depth > MaxDepth {
+		d.fail("nested more than %d deep", MaxDepth)
+	}
+	switch typ {
+	case TagByte:
+		return int8(d.byte())
+	case TagShort:
+		return int16(d.uint16())
+	case TagInt:
+		return int32(d.uint32())
+	case TagLong:
+		return int64(d.uint64())
+	case TagFloat:
+		return math.Float32frombits(d.uint32())
+	case TagDouble:
+		return math.Float64frombits(d.uint64())
+	case TagByteArray:
+		return append([]byte(nil), d.next(d.length(1))...)
+	case TagString:
+		return d.string()
+	case TagList:
+		elemType := d.byte()
+		// Every element takes at least one byte, so this limits the list to the size of the data
+		n := d.length(1)
+		if elemType == TagEnd && n > 0 {
+			d.fail("list of end tags")
+		}
+		list := make([]interface{}, n)
+		for i := range list {
+			list[i] = d.payload(elemType, depth+1)
+		}
+		return list
+	case TagCompound:
+		return d.compound(depth)
+	case TagIntArray:
+		a := make([]int32, d.length(4))
+		for i := range a {
+			a[i] = int32(d.uint32())
+		}
+		return a
+	case TagLongArray:
+		a := make([]int64, d.length(8))
+		for i := range a {
+			a[i] = int64(d.uint64())
+		}
+		return a
+	default:
+		d.fail("unknown tag type %d", typ)
+		return nil
+	}
+}
+
+// Decodes Java's modified UTF-8, which encodes NUL as two bytes and characters outside the Basic Multilingual Plane as surrogate pairs.
+// Invalid sequences are replaced with U+FFFD.
+func decodeModifiedUTF8(b []byte) string {
+	units := make([]uint16, 0, len(b))
+	for i := 0; i < len(b); {
+		c := b[i]
+		switch {
+		case c < 0x80:
+			units = append(units, uint16(c))
+			i++
+		case c&0xe0 == 0xc0 && i+1 < len(b) && b[i+1]&0xc0 == 0x80:
+			units = append(units, uint16(c&0x1f)<<6|uint16(b[i+1]&0x3f))
+			i += 2
+		case c&0xf0 == 0xe0 && i+2 < len(b) && b[i+1]&0xc0 == 0x80 && b[i+2]&0xc0 == 0x80:
+			units = append(units, uint16(c&0x0f)<<12|uint16(b[i+1]&0x3f)<<6|uint16(b[i+2]&0x3f))
+			i += 3
+		default:
+			units = append(units, 0xfffd)
+			i++
+		}
+	}
+	return string(utf16.Decode(units))
+}
+
+// Follows a path of compound names from root, returning the value at the end, or nil if there is none
+func Lookup(root map[string]interface{}, path ...string) interface{} {
+	var v interface{} = root
+	for _, name := range path {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		v = m[name]
+	}
+	return v
+}
@@ -0,0 +1,203 @@
+package nbt
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/binary"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// Encodes a value as the payload of a tag, returning the tag's type
+func encode(buf *bytes.Buffer, v interface{}) byte {
+	be := binary.BigEndian
+	switch v := v.(type) {
+	case int8:
+		buf.WriteByte(byte(v))
+		return TagByte
+	case int16:
+		binary.Write(buf, be, v)
+		return TagShort
+	case int32:
+		binary.Write(buf, be, v)
+		return TagInt
+	case int64:
+		binary.Write(buf, be, v)
+		return TagLong
+	case float32:
+		binary.Write(buf, be, v)
+		return TagFloat
+	case float64:
+		binary.Write(buf, be, v)
+		return TagDouble
+	case []byte:
+		binary.Write(buf, be, int32(len(v)))
+		buf.Write(v)
+		return TagByteArray
+	case string:
+		binary.Write(buf, be, uint16(len(v)))
+		buf.WriteString(v)
+		return TagString
+	case []interface{}:
+		var elems bytes.Buffer
+		typ := TagEnd
+		for _, elem := range v {
+			typ = encode(&elems, elem)
+		}
+		buf.WriteByte(typ)
+		binary.Write(buf, be, int32(len(v)))
+		buf.Write(elems.Bytes())
+		return TagList
+	case map[string]interface{}:
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			var payload bytes.Buffer
+			buf.WriteByte(encode(&payload, v[name]))
+			encode(buf, name)
+			buf.Write(payload.Bytes())
+		}
+		buf.WriteByte(TagEnd)
+		return TagCompound
+	case []int32:
+		binary.Write(buf, be, int32(len(v)))
+		binary.Write(buf, be, v)
+		return TagIntArray
+	case []int64:
+		binary.Write(buf, be, int32(len(v)))
+		binary.Write(buf, be, v)
+		return TagLongArray
+	}
+	panic("unsupported type")
+}
+
+func encodeRoot(name string, root map[string]interface{}) []byte {
+	var buf bytes.Buffer
+	buf.WriteByte(TagCompound)
+	encode(&buf, name)
+	encode(&buf, root)
+	return buf.Bytes()
+}
+
+func TestRead(t *testing.T) {
+	root := map[string]interface{}{
+		"Data": map[string]interface{}{
+			"byte":      int8(-5),
+			"short":     int16(-300),
+			"int":       int32(70000),
+			"long":      int64(-1234567890123456789),
+			"float":     float32(1.5),
+			"double":    math.Pi,
+			"bytes":     []byte{1, 2, 3},
+			"string":    "hello",
+			"list":      []interface{}{int32(1), int32(2)},
+			"empty":     []interface{}{},
+			"compounds": []interface{}{map[string]interface{}{"a": int8(1)}, map[string]interface{}{}},
+			"ints":      []int32{-1, 0, 1},
+			"longs":     []int64{math.MinInt64, math.MaxInt64},
+		},
+	}
+	data := encodeRoot("", root)
+
+	var gz, zl bytes.Buffer
+	gw := gzip.NewWriter(&gz)
+	gw.Write(data)
+	gw.Close()
+	zw := zlib.NewWriter(&zl)
+	zw.Write(data)
+	zw.Close()
+
+	for _, file := range [][]byte{data, gz.Bytes(), zl.Bytes()} {
+		name, got, err := Read(bytes.NewReader(file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if name != "" {
+			t.Errorf("Expected an empty root name, got %q", name)
+		}
+		if !reflect.DeepEqual(got, root) {
+			t.Errorf("Decoded wrong values: %#v", got)
+		}
+	}
+
+	if seed := Lookup(root, "Data", "long"); seed != int64(-1234567890123456789) {
+		t.Errorf("Lookup found %v", seed)
+	}
+	if v := Lookup(root, "Data", "long", "more"); v != nil {
+		t.Errorf("Lookup through a long found %v", v)
+	}
+	if v := Lookup(root, "Missing", "long"); v != nil {
+		t.Errorf("Lookup of a missing compound found %v", v)
+	}
+}
+
+func TestModifiedUTF8(t *testing.T) {
+	cases := map[string]string{
+		"plain":                    "plain",
+		"\xc0\x80":                 "\x00",
+		"caf\xc3\xa9":              "café",
+		"\xe2\x82\xac":             "€",
+		"\xed\xa0\xbd\xed\xb8\x80": "😀",
+		"bad\xff":                  "bad�",
+		"\xc3":                     "�",
+	}
+	for in, expected := range cases {
+		if got := decodeModifiedUTF8([]byte(in)); got != expected {
+			t.Errorf("%q: expected %q, got %q", in, expected, got)
+		}
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	valid := encodeRoot("root", map[string]interface{}{"a": []int64{1, 2}})
+	// Compounds nested n deep, but otherwise valid
+	nested := func(n int) []byte {
+		data := []byte{TagCompound, 0, 0}
+		for i := 0; i < n; i++ {
+			data = append(data, TagCompound, 0, 0)
+		}
+		return append(data, make([]byte, n+1)...)
+	}
+	// Lists nested n deep in the root compound
+	nestedLists := func(n int) []byte {
+		data := []byte{TagCompound, 0, 0, TagList, 0, 0}
+		for i := 1; i < n; i++ {
+			data = append(data, TagList, 0, 0, 0, 1)
+		}
+		return append(data, TagByte, 0, 0, 0, 0, TagEnd)
+	}
+	deep := nested(MaxDepth + 1)
+
+	cases := map[string][]byte{
+		"empty":        {},
+		"not compound": {TagInt, 0, 0, 0, 0, 0, 1},
+		"truncated":    valid[:len(valid)-3],
+		"no end":       valid[:len(valid)-1],
+		"unknown tag":  {TagCompound, 0, 0, 99, 0, 0},
+		"negative":     {TagCompound, 0, 0, TagIntArray, 0, 0, 0xff, 0xff, 0xff, 0xff},
+		"huge":         {TagCompound, 0, 0, TagLongArray, 0, 0, 0x7f, 0xff, 0xff, 0xff, 0, 0, 0, 0},
+		"huge list":    {TagCompound, 0, 0, TagList, 0, 0, TagByte, 0x7f, 0xff, 0xff, 0xff, 0},
+		"end list":     {TagCompound, 0, 0, TagList, 0, 0, TagEnd, 0, 0, 0, 1, 0},
+		"too deep":     deep,
+		"deep lists":   nestedLists(MaxDepth + 1),
+	}
+	for name, data := range cases {
+		if _, _, err := Decode(data); err == nil {
+			t.Errorf("%s: expected error", name)
+		} else if !strings.HasPrefix(err.Error(), "Invalid NBT") {
+			t.Errorf("%s: unexpected error %v", name, err)
+		}
+	}
+	for _, data := range [][]byte{valid, nested(MaxDepth), nestedLists(MaxDepth)} {
+		if _, _, err := Decode(data); err != nil {
+			t.Error(err)
+		}
+	}
+}
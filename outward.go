@@ -0,0 +1,112 @@
+package slimy
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// The width of each ring searched by SearchOutward, in chunks
+const RingWidth = 64
+
+// Searches square rings of increasing radius around cx, cz, out to maxRadius chunks from the centre along each axis.
+// Results are sorted nearest first, by distance from the centre.
+// Also returns the radius that was searched in full, or -1 if the search was cancelled before the first ring was finished.
+//
+// If opts.Limit is positive, the search stops as soon as the nearest Limit matches are known, and only those are returned.
+// Otherwise, every match within maxRadius is returned.
+// If opts.Stream is set, each match is streamed as soon as it is found, and also returned so that Limit can still stop the search early.
+// Every match in the rings searched is streamed, so there may be more than Limit of them.
+// Cancelling ctx, for example with a deadline, stops the search early and returns everything found so far.
+// Every Searcher can be searched this way, since each ring is made of rectangles.
+func SearchOutward(ctx context.Context, s Searcher, cx, cz, maxRadius int32, threshold Threshold, worldSeed int64, opts Options) (results []Result, radius int32, err error) {
+	side := 2*int64(maxRadius) + 1
+	total := side * side
+	start := time.Now()
+	done := int64(0)
+	lastProgress := start
+
+	radius = -1
+	for radius < maxRadius {
+		outer := radius + RingWidth
+		if outer > maxRadius {
+			outer = maxRadius
+		}
+
+		for _, r := range ringRects(cx, cz, radius, outer) {
+			var rectOpts Options
+			if opts.Stream != nil {
+				rectOpts.Stream = func(r Result) {
+					results = append(results, r)
+					opts.Stream(r)
+				}
+			}
+			if opts.Progress != nil {
+				before := done
+				rectOpts.Progress = func(p Progress) {
+					// Each rectangle reports when it finishes, which would be too often for small rings
+					if now := time.Now(); now.Sub(lastProgress) >= ProgressInterval {
+						lastProgress = now
+						opts.Progress(Progress{before + p.Scanned, total, now.Sub(start)})
+					}
+				}
+			}
+			found, err := s.SearchContext(ctx, r[0], r[1], r[2], r[3], threshold, worldSeed, rectOpts)
+			results = append(results, found...)
+			if err != nil {
+				sortByDistance(results, cx, cz, threshold.Order())
+				return results, radius, err
+			}
+			done += int64(r[2]-r[0]) * int64(r[3]-r[1])
+		}
+		radius = outer
+
+		sortByDistance(results, cx, cz, threshold.Order())
+		if opts.Limit > 0 && len(results) >= opts.Limit {
+			// Anything outside the searched square is further than the radius, so the nearest matches are known once they're all within it
+			last := results[opts.Limit-1]
+			if distance2(last, cx, cz) <= int64(radius)*int64(radius) {
+				break
+			}
+		}
+	}
+
+	if opts.Progress != nil {
+		opts.Progress(Progress{done, total, time.Since(start)})
+	}
+	if opts.Limit > 0 && len(results) > opts.Limit {
+		results = results[:opts.Limit]
+	}
+	return results, radius, nil
+}
+
+// Returns the rectangles covering the square ring of centres more than inner and at most outer chunks from the centre along either axis, as x0, z0, x1, z1 with the ends exclusive.
+// An inner radius of -1 gives the whole square.
+func ringRects(cx, cz, inner, outer int32) [][4]int32 {
+	if inner < 0 {
+		return [][4]int32{{cx - outer, cz - outer, cx + outer + 1, cz + outer + 1}}
+	}
+	return [][4]int32{
+		{cx - outer, cz - outer, cx + outer + 1, cz - inner},         // Top, including corners
+		{cx - outer, cz + inner + 1, cx + outer + 1, cz + outer + 1}, // Bottom, including corners
+		{cx - outer, cz - inner, cx - inner, cz + inner + 1},         // Left
+		{cx + inner + 1, cz - inner, cx + outer + 1, cz + inner + 1}, // Right
+	}
+}
+
+// Returns the squared distance of a result from a point
+func distance2(r Result, cx, cz int32) int64 {
+	dx, dz := int64(r.X)-int64(cx), int64(r.Z)-int64(cz)
+	return dx*dx + dz*dz
+}
+
+// Sorts results nearest first, then in the usual order
+func sortByDistance(results []Result, cx, cz int32, order Order) {
+	sort.Slice(results, func(i, j int) bool {
+		di, dj := distance2(results[i], cx, cz), distance2(results[j], cx, cz)
+		if di != dj {
+			return di < dj
+		}
+		return results[i].OrderBefore(results[j], order)
+	})
+}
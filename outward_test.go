@@ -0,0 +1,154 @@
+package slimy
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// A searcher that matches a fixed pattern of positions, and counts how often each position is searched
+type patternSearcher struct {
+	searched map[[2]int32]int
+}
+
+func (s *patternSearcher) match(x, z int32) bool {
+	return (x*7+z*13)%61 == 0
+}
+
+func (s *patternSearcher) Search(x0, z0, x1, z1 int32, threshold Threshold, worldSeed int64) []Result {
+	results, _ := s.SearchContext(context.Background(), x0, z0, x1, z1, threshold, worldSeed, Options{})
+	return results
+}
+func (s *patternSearcher) SearchContext(ctx context.Context, x0, z0, x1, z1 int32, threshold Threshold, worldSeed int64, opts Options) (results []Result, err error) {
+	for z := z0; z < z1; z++ {
+		for x := x0; x < x1; x++ {
+			s.searched[[2]int32{x, z}]++
+			if s.match(x, z) {
+				results = append(results, Result{x, z, 1, 1})
+			}
+		}
+	}
+	return results, ctx.Err()
+}
+func (s *patternSearcher) SearchSeeds(ctx context.Context, x0, z0, x1, z1 int32, threshold Threshold, worldSeeds []int64, opts Options) ([]SeedResults, error) {
+	return nil, nil
+}
+func (s *patternSearcher) Destroy() {}
+
+func TestSearchOutwardCoverage(t *testing.T) {
+	s := &patternSearcher{make(map[[2]int32]int)}
+	const maxRadius = 2*RingWidth + 10
+	results, radius, err := SearchOutward(context.Background(), s, 5, -3, maxRadius, AtLeast(1), 0, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if radius != maxRadius {
+		t.Errorf("Expected radius %d, got %d", maxRadius, radius)
+	}
+
+	// Every position in the square is searched exactly once
+	if len(s.searched) != (2*maxRadius+1)*(2*maxRadius+1) {
+		t.Errorf("Searched %d positions, expected %d", len(s.searched), (2*maxRadius+1)*(2*maxRadius+1))
+	}
+	for pos, n := range s.searched {
+		if n != 1 || pos[0] < 5-maxRadius || pos[0] > 5+maxRadius || pos[1] < -3-maxRadius || pos[1] > -3+maxRadius {
+			t.Fatalf("Position %v searched %d times", pos, n)
+		}
+	}
+
+	expected := 0
+	for pos := range s.searched {
+		if s.match(pos[0], pos[1]) {
+			expected++
+		}
+	}
+	if len(results) != expected {
+		t.Fatalf("Expected %d results, got %d", expected, len(results))
+	}
+	for i := 1; i < len(results); i++ {
+		if distance2(results[i], 5, -3) < distance2(results[i-1], 5, -3) {
+			t.Fatalf("Results out of order at index %d", i)
+		}
+	}
+}
+
+func TestSearchOutwardLimit(t *testing.T) {
+	full, _, _ := SearchOutward(context.Background(), &patternSearcher{make(map[[2]int32]int)}, 100, 40, 500, AtLeast(1), 0, Options{})
+
+	for _, limit := range []int{1, 20, 500} {
+		s := &patternSearcher{make(map[[2]int32]int)}
+		results, radius, err := SearchOutward(context.Background(), s, 100, 40, 500, AtLeast(1), 0, Options{Limit: limit})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(results) != limit {
+			t.Fatalf("Expected %d results, got %d", limit, len(results))
+		}
+		for i := range results {
+			if results[i] != full[i] {
+				t.Fatalf("Limit %d: result %d is %v, expected %v", limit, i, results[i], full[i])
+			}
+		}
+		if radius >= 500 && limit < 500 {
+			t.Errorf("Limit %d: searched the full radius", limit)
+		}
+		if side := 2*int(radius) + 1; len(s.searched) != side*side {
+			t.Errorf("Limit %d: searched %d positions, expected %d", limit, len(s.searched), side*side)
+		}
+	}
+}
+
+func TestSearchOutwardCancel(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Nanosecond)
+	defer cancel()
+	<-ctx.Done()
+	_, radius, err := SearchOutward(ctx, &patternSearcher{make(map[[2]int32]int)}, 0, 0, 500, AtLeast(1), 0, Options{})
+	if err == nil {
+		t.Error("Expected an error")
+	}
+	if radius != -1 {
+		t.Errorf("Expected radius -1, got %d", radius)
+	}
+}
+
+func TestSearchOutwardStreamLimit(t *testing.T) {
+	full, _, _ := SearchOutward(context.Background(), &patternSearcher{make(map[[2]int32]int)}, 100, 40, 500, AtLeast(1), 0, Options{})
+
+	s := &streamingPatternSearcher{patternSearcher{make(map[[2]int32]int)}}
+	streamed := 0
+	opts := Options{Limit: 20, Stream: func(Result) { streamed++ }}
+	results, radius, err := SearchOutward(context.Background(), s, 100, 40, 500, AtLeast(1), 0, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if radius >= 500 {
+		t.Error("Searched the full radius")
+	}
+	if streamed < len(results) {
+		t.Errorf("Streamed %d results, but returned %d", streamed, len(results))
+	}
+	if len(results) != 20 {
+		t.Fatalf("Expected 20 results, got %d", len(results))
+	}
+	for i := range results {
+		if results[i] != full[i] {
+			t.Fatalf("Result %d is %v, expected %v", i, results[i], full[i])
+		}
+	}
+}
+
+// A patternSearcher that streams its results when asked to
+type streamingPatternSearcher struct {
+	patternSearcher
+}
+
+func (s *streamingPatternSearcher) SearchContext(ctx context.Context, x0, z0, x1, z1 int32, threshold Threshold, worldSeed int64, opts Options) ([]Result, error) {
+	results, err := s.patternSearcher.SearchContext(ctx, x0, z0, x1, z1, threshold, worldSeed, opts)
+	if opts.Stream == nil {
+		return results, err
+	}
+	for _, r := range results {
+		opts.Stream(r)
+	}
+	return nil, err
+}
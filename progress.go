@@ -0,0 +1,74 @@
+package slimy
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// How often Options.Progress is called during a search
+const ProgressInterval = 500 * time.Millisecond
+
+// The progress of a running search.
+// Chunks are counted as mask centres, so the total is the area of the search region.
+type Progress struct {
+	Scanned, Total int64
+	Elapsed        time.Duration
+}
+
+// Estimates the time remaining, assuming the search continues at the same rate.
+// Returns zero if nothing has been scanned yet.
+func (p Progress) ETA() time.Duration {
+	if p.Scanned <= 0 {
+		return 0
+	}
+	return time.Duration(float64(p.Elapsed) * float64(p.Total-p.Scanned) / float64(p.Scanned))
+}
+
+// Collects progress from a search's workers and reports it at most once per ProgressInterval.
+// Safe for concurrent use. A nil *ProgressReporter does nothing.
+type ProgressReporter struct {
+	fn      func(Progress)
+	total   int64
+	start   time.Time
+	scanned int64 // Accessed atomically
+
+	mu   sync.Mutex
+	last time.Time
+}
+
+// Creates a ProgressReporter for a search of the given number of chunks.
+// Returns nil if fn is nil.
+func NewProgressReporter(fn func(Progress), total int64) *ProgressReporter {
+	if fn == nil {
+		return nil
+	}
+	now := time.Now()
+	return &ProgressReporter{fn: fn, total: total, start: now, last: now}
+}
+
+// Records that n more chunks have been scanned
+func (r *ProgressReporter) Add(n int64) {
+	if r == nil {
+		return
+	}
+	scanned := atomic.AddInt64(&r.scanned, n)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	if now.Sub(r.last) >= ProgressInterval {
+		r.last = now
+		r.fn(Progress{scanned, r.total, now.Sub(r.start)})
+	}
+}
+
+// Reports the final progress of the search
+func (r *ProgressReporter) Finish() {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.fn(Progress{atomic.LoadInt64(&r.scanned), r.total, time.Since(r.start)})
+}
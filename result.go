@@ -1,17 +1,25 @@
 package slimy
 
+import (
+	"container/heap"
+	"sort"
+)
+
 type Result struct {
 	X, Z  int32
-	Count uint
+	Count uint // The number of slime chunks in the mask
+	// The sum of the weights of the slime chunks in the mask.
+	// Equal to Count unless the mask is weighted.
+	Score float64
 }
 
-func (a Result) OrderBefore(b Result, direction int) bool {
-	// Sort by count
-	if a.Count != b.Count {
-		if direction >= 0 {
-			return a.Count > b.Count
+func (a Result) OrderBefore(b Result, order Order) bool {
+	// Sort by score
+	if a.Score != b.Score {
+		if order == Ascending {
+			return a.Score < b.Score
 		} else {
-			return a.Count < b.Count
+			return a.Score > b.Score
 		}
 	}
 
@@ -31,3 +39,99 @@ func (a Result) OrderBefore(b Result, direction int) bool {
 	}
 	return false
 }
+
+// Sorts results so that the best come first, according to OrderBefore
+func SortResults(results []Result, order Order) {
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].OrderBefore(results[j], order)
+	})
+}
+
+// A player position found by an AFK search, in block coordinates
+type BlockResult struct {
+	X, Z int32
+	// The number of slime chunks around the player, each weighted by the fraction of its columns in the spawn ring
+	Score float64
+}
+
+func (a BlockResult) OrderBefore(b BlockResult, order Order) bool {
+	// Sort by score
+	if a.Score != b.Score {
+		if order == Ascending {
+			return a.Score < b.Score
+		} else {
+			return a.Score > b.Score
+		}
+	}
+
+	// Then by distance from 0,0, which can overflow int32 for block coordinates
+	aD2 := int64(a.X)*int64(a.X) + int64(a.Z)*int64(a.Z)
+	bD2 := int64(b.X)*int64(b.X) + int64(b.Z)*int64(b.Z)
+	if aD2 != bD2 {
+		return aD2 < bD2
+	}
+
+	// Then finally break ties by coordinate
+	if a.X != b.X {
+		return a.X < b.X
+	}
+	if a.Z != b.Z {
+		return a.Z < b.Z
+	}
+	return false
+}
+
+// Sorts block results so that the best come first, according to OrderBefore
+func SortBlockResults(results []BlockResult, order Order) {
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].OrderBefore(results[j], order)
+	})
+}
+
+// Keeps the best n results added to it, according to OrderBefore.
+// Not safe for concurrent use.
+type TopK struct {
+	n     int
+	order Order
+	heap  resultHeap
+}
+
+func NewTopK(n int, order Order) *TopK {
+	return &TopK{n, order, resultHeap{order: order}}
+}
+
+func (t *TopK) Add(results ...Result) {
+	for _, r := range results {
+		if len(t.heap.results) < t.n {
+			heap.Push(&t.heap, r)
+		} else if t.n > 0 && r.OrderBefore(t.heap.results[0], t.order) {
+			t.heap.results[0] = r
+			heap.Fix(&t.heap, 0)
+		}
+	}
+}
+
+// Returns the kept results, best first
+func (t *TopK) Results() []Result {
+	results := append([]Result(nil), t.heap.results...)
+	SortResults(results, t.order)
+	return results
+}
+
+// A heap with the worst result at the top, so it can be replaced when a better one is found
+type resultHeap struct {
+	order   Order
+	results []Result
+}
+
+func (h resultHeap) Len() int           { return len(h.results) }
+func (h resultHeap) Less(i, j int) bool { return h.results[j].OrderBefore(h.results[i], h.order) }
+func (h resultHeap) Swap(i, j int)      { h.results[i], h.results[j] = h.results[j], h.results[i] }
+func (h *resultHeap) Push(x interface{}) {
+	h.results = append(h.results, x.(Result))
+}
+func (h *resultHeap) Pop() interface{} {
+	r := h.results[len(h.results)-1]
+	h.results = h.results[:len(h.results)-1]
+	return r
+}
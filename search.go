@@ -1,6 +1,61 @@
 package slimy
 
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
 type Searcher interface {
-	Search(x0, z0, x1, z1 int32, threshold int, worldSeed int64) []Result
+	Search(x0, z0, x1, z1 int32, threshold Threshold, worldSeed int64) []Result
+	// Like Search, but stops early if ctx is cancelled.
+	// A cancelled search returns the results found so far, along with the context's error.
+	SearchContext(ctx context.Context, x0, z0, x1, z1 int32, threshold Threshold, worldSeed int64, opts Options) ([]Result, error)
+	// Searches the same area of several worlds, returning results for each seed in the same order as the seeds.
+	// Options.Stream is not supported, and Options.Limit applies to each seed separately.
+	SearchSeeds(ctx context.Context, x0, z0, x1, z1 int32, threshold Threshold, worldSeeds []int64, opts Options) ([]SeedResults, error)
 	Destroy()
 }
+
+// The results of searching one seed in a batch
+type SeedResults struct {
+	Seed    int64
+	Results []Result
+}
+
+// The most seeds SeedRange returns. Each seed's results are kept until the whole batch is searched, so larger batches should be split up.
+const MaxSeeds = 1 << 24
+
+// Returns every seed from start up to and including end, or an error if there are more than MaxSeeds of them
+func SeedRange(start, end int64) ([]int64, error) {
+	if start > end {
+		return nil, errors.New("Seed range start is after its end")
+	}
+	// The difference may overflow an int64, but not a uint64
+	if uint64(end)-uint64(start) >= MaxSeeds {
+		return nil, fmt.Errorf("Seed range %d..%d has more than %d seeds", start, end, MaxSeeds)
+	}
+	seeds := make([]int64, 0, end-start+1)
+	for seed := start; ; seed++ {
+		seeds = append(seeds, seed)
+		if seed == end {
+			return seeds, nil
+		}
+	}
+}
+
+// Options for Searcher.SearchContext
+type Options struct {
+	// If not nil, called periodically with the progress of the search.
+	// May be called from any goroutine, but never concurrently.
+	Progress func(Progress)
+
+	// If not nil, each result is passed to Stream as soon as it is found, rather than being collected and returned at the end.
+	// Streamed results are in no particular order.
+	// May be called from any goroutine, but never concurrently.
+	Stream func(Result)
+
+	// If positive, only the best Limit results are returned.
+	// Has no effect on streamed results.
+	Limit int
+}
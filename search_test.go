@@ -0,0 +1,32 @@
+package slimy
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSeedRange(t *testing.T) {
+	cases := []struct {
+		start, end int64
+		n          int
+	}{
+		{-2, 2, 5},
+		{7, 7, 1},
+		{math.MaxInt64 - 1, math.MaxInt64, 2},
+		{math.MinInt64, math.MinInt64 + MaxSeeds - 1, MaxSeeds},
+	}
+	for _, c := range cases {
+		seeds, err := SeedRange(c.start, c.end)
+		if err != nil {
+			t.Errorf("%d..%d: %v", c.start, c.end, err)
+		} else if len(seeds) != c.n || seeds[0] != c.start || seeds[len(seeds)-1] != c.end {
+			t.Errorf("%d..%d: incorrect seeds", c.start, c.end)
+		}
+	}
+
+	for _, c := range [][2]int64{{2, 1}, {0, MaxSeeds}, {0, math.MaxInt64}, {math.MinInt64, math.MaxInt64}} {
+		if _, err := SeedRange(c[0], c[1]); err == nil {
+			t.Errorf("%d..%d: expected error", c[0], c[1])
+		}
+	}
+}
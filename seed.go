@@ -0,0 +1,98 @@
+package slimy
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+)
+
+// Parses a world seed the way Minecraft: Java Edition does when creating a world.
+// Surrounding whitespace and control characters are ignored.
+// Text that is a decimal number in the range of a Java long is that number, including any sign and leading zeros.
+// Any other text, including numbers that overflow, is hashed with JavaStringHash.
+// A blank seed gives an error, since the game picks a random seed for it.
+func ParseSeed(s string) (int64, error) {
+	s = javaTrim(s)
+	if s == "" {
+		return 0, errors.New("Blank seeds are random, so there is no seed to search")
+	}
+	if seed, ok := parseJavaLong(s); ok {
+		return seed, nil
+	}
+	return int64(JavaStringHash(s)), nil
+}
+
+// Reports whether ParseSeed would hash s rather than reading it as a number
+func IsTextSeed(s string) bool {
+	s = javaTrim(s)
+	_, ok := parseJavaLong(s)
+	return s != "" && !ok
+}
+
+// Returns the hash of a string, as computed by Java's String.hashCode.
+// The hash is over UTF-16 code units, so characters outside the Basic Multilingual Plane count as two.
+func JavaStringHash(s string) (h int32) {
+	for _, c := range utf16.Encode([]rune(s)) {
+		h = 31*h + int32(c)
+	}
+	return h
+}
+
+// Removes leading and trailing characters up to and including space, like Java's String.trim
+func javaTrim(s string) string {
+	return strings.TrimFunc(s, func(r rune) bool { return r <= ' ' })
+}
+
+// Parses a decimal number like Java's Long.parseLong.
+// An optional sign is allowed, and digits may be from any script in the Basic Multilingual Plane.
+func parseJavaLong(s string) (n int64, ok bool) {
+	neg := false
+	if s != "" && (s[0] == '-' || s[0] == '+') {
+		neg = s[0] == '-'
+		s = s[1:]
+	}
+	if s == "" {
+		return 0, false
+	}
+
+	// Accumulate negatively, since the most negative long has no positive counterpart
+	limit := int64(-math.MaxInt64)
+	if neg {
+		limit = math.MinInt64
+	}
+	for _, r := range s {
+		d := digitValue(r)
+		if d < 0 || n < limit/10 {
+			return 0, false
+		}
+		n *= 10
+		if n < limit+int64(d) {
+			return 0, false
+		}
+		n -= int64(d)
+	}
+	if !neg {
+		n = -n
+	}
+	return n, true
+}
+
+// Returns the value of a decimal digit from any script, or -1 if r is not one.
+// Like Java's Character.digit(char, int), this only accepts digits in the Basic Multilingual Plane,
+// since Java sees any other character as two UTF-16 code units, neither of which is a digit.
+func digitValue(r rune) int {
+	if '0' <= r && r <= '9' {
+		return int(r - '0')
+	}
+	if r > 0xffff || !unicode.IsDigit(r) {
+		return -1
+	}
+	// Decimal digits are encoded in runs of 0 to 9, sometimes several in a row
+	start := r
+	for unicode.IsDigit(start - 1) {
+		start--
+	}
+	return int(r-start) % 10
+}
@@ -0,0 +1,71 @@
+package slimy
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParseSeed(t *testing.T) {
+	cases := []struct {
+		s        string
+		expected int64
+	}{
+		// Numbers
+		{"12345", 12345},
+		{"-12345", -12345},
+		{"+12345", 12345},
+		{"007", 7},
+		{"-0", 0},
+		{"0", 0},
+		{" \t42\n", 42},
+		{"9223372036854775807", math.MaxInt64},
+		{"-9223372036854775808", math.MinInt64},
+		{"١٢٣", 123}, // Arabic-Indic digits
+		{"１２", 12},   // Fullwidth digits
+
+		// Text
+		{"hello", 99162322},
+		{"ABC", 64578},
+		{"Aa", 2112},
+		{"BB", 2112},
+		{"polygenelubricants", math.MinInt32},
+		{"hello world", 1794106052},
+		{"😀", 1772899}, // A surrogate pair in UTF-16
+		{"  hello  ", 99162322},
+
+		// Not quite numbers
+		{"9223372036854775808", -1773151197},
+		{"-9223372036854775809", 1304595159},
+		{"1_000", 48130338},
+		{"0x10", 1546855},
+		{"1e5", 50273},
+		{"+", 43},
+		{"-", 45},
+		{"4 2", 51014},
+		{"𝟎", 1773113}, // Mathematical digits are outside the Basic Multilingual Plane, so Java doesn't see them as digits
+		{"1𝟐", 1820204},
+		{" 42", 155422}, // A non-breaking space isn't trimmed
+	}
+	for _, c := range cases {
+		seed, err := ParseSeed(c.s)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", c.s, err)
+		} else if seed != c.expected {
+			t.Errorf("%q: expected %d, got %d", c.s, c.expected, seed)
+		}
+	}
+
+	for _, s := range []string{"", "   ", "\t\r\n"} {
+		if _, err := ParseSeed(s); err == nil {
+			t.Errorf("%q: expected error", s)
+		}
+	}
+}
+
+func TestIsTextSeed(t *testing.T) {
+	for s, expected := range map[string]bool{"123": false, " -5 ": false, "abc": true, "99999999999999999999": true, "": false} {
+		if IsTextSeed(s) != expected {
+			t.Errorf("%q: expected %t", s, expected)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package slimy
+
+import (
+	"image"
+	"math"
+
+	"github.com/vktec/slimy/util"
+)
+
+// The probability that any given chunk is a slime chunk, in both editions
+const SlimeChance = 0.1
+
+// The distribution of a mask's score at a random position, assuming each chunk is independently a slime chunk with probability SlimeChance.
+// Scores are counted in whole units: one per slime chunk for an ordinary mask, or one per step of weight for a weighted mask.
+type Distribution struct {
+	Unit float64   // The score of one unit
+	P    []float64 // P[i] is the probability of a score of exactly i units
+
+	atLeast []float64 // atLeast[i] is the probability of a score of at least i units
+	atMost  []float64 // atMost[i] is the probability of a score of at most i units
+}
+
+// Computes the score distribution of a mask image.
+// If weighted is true, each chunk is weighted as with a weighted search; otherwise the distribution is binomial.
+func NewDistribution(mask image.Image, weighted bool) *Distribution {
+	dim := mask.Bounds().Canon()
+	if !weighted {
+		n := 0
+		for y := dim.Min.Y; y < dim.Max.Y; y++ {
+			for x := dim.Min.X; x < dim.Max.X; x++ {
+				if util.InMask(mask.At(x, y)) {
+					n++
+				}
+			}
+		}
+		return newDistribution(1, binomial(n, SlimeChance))
+	}
+
+	// Convolve the distribution of each chunk's contribution in turn
+	p := []float64{1}
+	for y := dim.Min.Y; y < dim.Max.Y; y++ {
+		for x := dim.Min.X; x < dim.Max.X; x++ {
+			w := int(util.MaskWeight(mask.At(x, y)))
+			if w == 0 {
+				continue
+			}
+			next := make([]float64, len(p)+w)
+			for i, pi := range p {
+				next[i] += pi * (1 - SlimeChance)
+				next[i+w] += pi * SlimeChance
+			}
+			p = next
+		}
+	}
+	return newDistribution(1.0/util.MaxMaskWeight, p)
+}
+
+func newDistribution(unit float64, p []float64) *Distribution {
+	// Summing from the unlikely end keeps small probabilities accurate
+	atLeast := make([]float64, len(p)+1)
+	for i := len(p) - 1; i >= 0; i-- {
+		atLeast[i] = atLeast[i+1] + p[i]
+	}
+	atMost := make([]float64, len(p))
+	sum := 0.0
+	for i := range p {
+		sum += p[i]
+		atMost[i] = sum
+	}
+	return &Distribution{unit, p, atLeast, atMost}
+}
+
+// Returns the probabilities of each number of successes out of n trials
+func binomial(n int, chance float64) []float64 {
+	p := make([]float64, n+1)
+	p[0] = 1
+	for i := 0; i < n; i++ {
+		for k := i + 1; k > 0; k-- {
+			p[k] = p[k]*(1-chance) + p[k-1]*chance
+		}
+		p[0] *= 1 - chance
+	}
+	return p
+}
+
+// Converts a score to a number of units
+func (d *Distribution) Units(score float64) int {
+	return int(math.Round(score / d.Unit))
+}
+
+// Returns the highest possible score
+func (d *Distribution) Max() float64 {
+	return float64(len(d.P)-1) * d.Unit
+}
+
+// Returns the mean score
+func (d *Distribution) Mean() (mean float64) {
+	for i, p := range d.P {
+		mean += float64(i) * p
+	}
+	return mean * d.Unit
+}
+
+// Returns the probability of a score at least as good as the given one at a random position.
+// For descending order that is the probability of scoring at least as much, and for ascending order at most as much.
+func (d *Distribution) PValue(score float64, order Order) float64 {
+	i := d.Units(score)
+	if order == Ascending {
+		switch {
+		case i < 0:
+			return 0
+		case i >= len(d.atMost):
+			return 1
+		}
+		// Rounding errors can push the sum slightly past 1
+		return math.Min(d.atMost[i], 1)
+	}
+	return d.tail(i)
+}
+
+// Returns the probability of a score of at least i units
+func (d *Distribution) tail(i int) float64 {
+	switch {
+	case i <= 0:
+		return 1
+	case i >= len(d.atLeast):
+		return 0
+	}
+	return math.Min(d.atLeast[i], 1)
+}
+
+// Returns how many random positions there are for each one that scores at least as well as the given score, which is 1 / PValue.
+// A score that can't happen has infinite rarity.
+func (d *Distribution) Rarity(score float64, order Order) float64 {
+	return 1 / d.PValue(score, order)
+}
+
+// Estimates the expected score of the best of the given number of positions.
+// Positions are treated as independent, which overlapping masks are not, so this is only an estimate.
+func (d *Distribution) ExpectedBest(positions int64, order Order) float64 {
+	if positions <= 0 {
+		return math.NaN()
+	}
+	// The expected value of a score in whole units is the sum over i >= 1 of the chance it is at least i
+	n := float64(positions)
+	expected := 0.0
+	for i := 1; i < len(d.P); i++ {
+		if order == Ascending {
+			// The worst of n positions is at least i only if they all are
+			expected += math.Pow(d.tail(i), n)
+		} else {
+			// The best of n positions is at least i unless they are all less
+			expected += -math.Expm1(n * math.Log1p(-d.tail(i)))
+		}
+	}
+	return expected * d.Unit
+}
+
+// Counts how many searched positions had each score
+type Histogram struct {
+	Unit   float64
+	Counts []int64 // Counts[i] is the number of positions with a score of exactly i units
+	Total  int64
+}
+
+// Creates a histogram with the same units as the distribution
+func (d *Distribution) NewHistogram() *Histogram {
+	return &Histogram{d.Unit, make([]int64, len(d.P)), 0}
+}
+
+// Counts a result's score. Not safe for concurrent use.
+func (h *Histogram) Add(r Result) {
+	i := int(math.Round(r.Score / h.Unit))
+	if i < 0 {
+		i = 0
+	}
+	for i >= len(h.Counts) {
+		h.Counts = append(h.Counts, 0)
+	}
+	h.Counts[i]++
+	h.Total++
+}
+
+// Returns the best score counted, or NaN if the histogram is empty
+func (h *Histogram) Best(order Order) float64 {
+	for i := range h.Counts {
+		if order == Descending {
+			i = len(h.Counts) - 1 - i
+		}
+		if h.Counts[i] > 0 {
+			return float64(i) * h.Unit
+		}
+	}
+	return math.NaN()
+}
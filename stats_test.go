@@ -0,0 +1,138 @@
+package slimy
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+
+	"github.com/vktec/slimy/util"
+)
+
+const probTolerance = 1e-12
+
+func TestDistributionBinomial(t *testing.T) {
+	d := NewDistribution(util.GenDonut(1, 8), false)
+	n := len(d.P) - 1
+	if n != 192 {
+		t.Fatalf("Expected 192 cells, got %d", n)
+	}
+	for k := 0; k <= n; k++ {
+		// Work in logs, since the binomial coefficients overflow
+		lgN, _ := math.Lgamma(float64(n + 1))
+		lgK, _ := math.Lgamma(float64(k + 1))
+		lgNK, _ := math.Lgamma(float64(n - k + 1))
+		expected := math.Exp(lgN - lgK - lgNK + float64(k)*math.Log(0.1) + float64(n-k)*math.Log(0.9))
+		if math.Abs(d.P[k]-expected) > probTolerance*math.Max(expected, 1e-300)+1e-300 {
+			t.Errorf("P(%d): expected %g, got %g", k, expected, d.P[k])
+		}
+	}
+	if mean := d.Mean(); math.Abs(mean-19.2) > 1e-9 {
+		t.Errorf("Expected mean 19.2, got %g", mean)
+	}
+}
+
+func TestDistributionWeighted(t *testing.T) {
+	weights := []uint8{255, 128, 64, 1}
+	img := image.NewGray(image.Rect(0, 0, len(weights), 1))
+	for i, w := range weights {
+		img.Set(i, 0, color.Gray{w})
+	}
+	d := NewDistribution(img, true)
+
+	// Add up every combination of slime chunks
+	expected := make([]float64, 255+128+64+1+1)
+	for set := 0; set < 1<<len(weights); set++ {
+		total, p := 0, 1.0
+		for i, w := range weights {
+			if set&(1<<i) != 0 {
+				total += int(w)
+				p *= SlimeChance
+			} else {
+				p *= 1 - SlimeChance
+			}
+		}
+		expected[total] += p
+	}
+	if len(d.P) != len(expected) {
+		t.Fatalf("Expected %d units, got %d", len(expected), len(d.P))
+	}
+	for i := range expected {
+		if math.Abs(d.P[i]-expected[i]) > probTolerance {
+			t.Errorf("P(%d): expected %g, got %g", i, expected[i], d.P[i])
+		}
+	}
+
+	// A weighted mask of full weights has the same distribution as an unweighted one
+	donut := util.GenDonut(1, 3)
+	plain, full := NewDistribution(donut, false), NewDistribution(donut, true)
+	for k, p := range plain.P {
+		if math.Abs(full.P[k*util.MaxMaskWeight]-p) > probTolerance {
+			t.Errorf("P(%d): expected %g, got %g", k, p, full.P[k*util.MaxMaskWeight])
+		}
+		if math.Abs(full.PValue(float64(k), Descending)-plain.PValue(float64(k), Descending)) > probTolerance {
+			t.Errorf("p-value of %d differs", k)
+		}
+	}
+}
+
+func TestPValue(t *testing.T) {
+	d := NewDistribution(util.GenDonut(1, 4), false)
+	n := len(d.P) - 1
+	for k := 0; k <= n; k++ {
+		var above, below float64
+		for i := k; i <= n; i++ {
+			above += d.P[i]
+		}
+		for i := 0; i <= k; i++ {
+			below += d.P[i]
+		}
+		if got := d.PValue(float64(k), Descending); math.Abs(got-above) > probTolerance*above {
+			t.Errorf("Descending p-value of %d: expected %g, got %g", k, above, got)
+		}
+		if got := d.PValue(float64(k), Ascending); math.Abs(got-below) > probTolerance*below {
+			t.Errorf("Ascending p-value of %d: expected %g, got %g", k, below, got)
+		}
+	}
+	if d.PValue(0, Descending) != 1 || d.PValue(float64(n+1), Descending) != 0 {
+		t.Error("Wrong p-value outside the possible scores")
+	}
+	if r := d.Rarity(float64(n), Descending); math.Abs(r/math.Pow(10, float64(n))-1) > 1e-12 {
+		t.Errorf("Expected every one of %d chunks to be one in 1e%d, got %g", n, n, r)
+	}
+}
+
+func TestExpectedBest(t *testing.T) {
+	d := NewDistribution(util.GenDonut(1, 8), false)
+	for _, order := range []Order{Descending, Ascending} {
+		// The best of one position is just the mean
+		if best := d.ExpectedBest(1, order); math.Abs(best-d.Mean()) > 1e-9 {
+			t.Errorf("Expected the best of 1 to be the mean %g, got %g", d.Mean(), best)
+		}
+	}
+	prev := d.Mean()
+	for n := int64(10); n <= 1e12; n *= 10 {
+		best := d.ExpectedBest(n, Descending)
+		if best <= prev || best > d.Max() {
+			t.Errorf("Expected best of %d: %g is out of order", n, best)
+		}
+		prev = best
+	}
+	if worst := d.ExpectedBest(1e6, Ascending); worst >= d.Mean() || worst < 0 {
+		t.Errorf("Expected worst of 1e6: %g is out of range", worst)
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	d := NewDistribution(util.GenDonut(1, 2), true)
+	h := d.NewHistogram()
+	for _, score := range []float64{0, 2, 2, 1 + 128.0/255} {
+		h.Add(Result{Score: score})
+	}
+	if h.Total != 4 || h.Counts[510] != 2 || h.Counts[383] != 1 || h.Counts[0] != 1 {
+		t.Errorf("Wrong counts: %d total", h.Total)
+	}
+	if h.Best(Descending) != 2 || h.Best(Ascending) != 0 {
+		t.Errorf("Wrong best scores: %g and %g", h.Best(Descending), h.Best(Ascending))
+	}
+}
@@ -0,0 +1,132 @@
+package slimy
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// The order in which results are sorted by count
+type Order int
+
+const (
+	Descending Order = iota // Highest counts first
+	Ascending               // Lowest counts first
+)
+
+// How a Threshold compares slime chunk counts
+type ThresholdMode int
+
+const (
+	AtLeastMode ThresholdMode = iota // Count >= Min
+	AtMostMode                       // Count <= Max
+	BetweenMode                      // Min <= Count <= Max
+)
+
+// Decides which slime chunk counts a search reports, and in which order.
+// The zero value accepts every count.
+type Threshold struct {
+	Mode     ThresholdMode
+	Min, Max uint
+}
+
+func AtLeast(n uint) Threshold {
+	return Threshold{Mode: AtLeastMode, Min: n}
+}
+func AtMost(n uint) Threshold {
+	return Threshold{Mode: AtMostMode, Max: n}
+}
+func Between(min, max uint) Threshold {
+	return Threshold{BetweenMode, min, max}
+}
+
+// Returns the inclusive range of counts accepted by the threshold
+func (t Threshold) Bounds() (min, max uint) {
+	switch t.Mode {
+	case AtMostMode:
+		return 0, t.Max
+	case BetweenMode:
+		return t.Min, t.Max
+	default:
+		return t.Min, math.MaxUint32
+	}
+}
+
+func (t Threshold) Check(count uint) bool {
+	min, max := t.Bounds()
+	return min <= count && count <= max
+}
+
+// Like Check, but for fractional scores
+func (t Threshold) CheckScore(score float64) bool {
+	min, max := t.Bounds()
+	return float64(min) <= score && score <= float64(max)
+}
+
+// Searching for at most some number of chunks prefers the lowest counts, otherwise the highest are preferred
+func (t Threshold) Order() Order {
+	if t.Mode == AtMostMode {
+		return Ascending
+	}
+	return Descending
+}
+
+func (t Threshold) String() string {
+	switch t.Mode {
+	case AtMostMode:
+		return fmt.Sprintf("<=%d", t.Max)
+	case BetweenMode:
+		return fmt.Sprintf("%d..%d", t.Min, t.Max)
+	default:
+		return fmt.Sprintf(">=%d", t.Min)
+	}
+}
+
+// Parses a threshold of one of the following forms:
+//
+//	N, >=N   at least N chunks
+//	<=N      at most N chunks
+//	-N       at most N chunks, for compatibility with older versions
+//	N..M     between N and M chunks inclusive
+//	=N       exactly N chunks
+func ParseThreshold(s string) (Threshold, error) {
+	s = strings.TrimSpace(s)
+	parse := func(s string) (uint, error) {
+		n, err := strconv.ParseUint(strings.TrimSpace(s), 10, 32)
+		return uint(n), err
+	}
+
+	switch {
+	case strings.HasPrefix(s, ">="):
+		n, err := parse(s[2:])
+		return AtLeast(n), err
+	case strings.HasPrefix(s, "<="):
+		n, err := parse(s[2:])
+		return AtMost(n), err
+	case strings.HasPrefix(s, "-"):
+		n, err := parse(s[1:])
+		return AtMost(n), err
+	case strings.HasPrefix(s, "="):
+		n, err := parse(s[1:])
+		return Between(n, n), err
+	case strings.Contains(s, ".."):
+		parts := strings.SplitN(s, "..", 2)
+		min, err := parse(parts[0])
+		if err != nil {
+			return Threshold{}, err
+		}
+		max, err := parse(parts[1])
+		if err != nil {
+			return Threshold{}, err
+		}
+		if min > max {
+			return Threshold{}, errors.New("Threshold minimum must not exceed maximum")
+		}
+		return Between(min, max), nil
+	default:
+		n, err := parse(s)
+		return AtLeast(n), err
+	}
+}
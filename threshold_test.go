@@ -0,0 +1,57 @@
+package slimy
+
+import "testing"
+
+func TestParseThreshold(t *testing.T) {
+	cases := []struct {
+		s        string
+		expected Threshold
+	}{
+		{"30", AtLeast(30)},
+		{">=30", AtLeast(30)},
+		{" >= 30 ", AtLeast(30)},
+		{"0", AtLeast(0)},
+		{"<=12", AtMost(12)},
+		{"-12", AtMost(12)},
+		{"30..40", Between(30, 40)},
+		{"=35", Between(35, 35)},
+	}
+	for _, c := range cases {
+		th, err := ParseThreshold(c.s)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", c.s, err)
+		} else if th != c.expected {
+			t.Errorf("%q: expected %v, got %v", c.s, c.expected, th)
+		}
+	}
+
+	for _, s := range []string{"", "abc", "40..30", "1..", ">=-3", "99999999999"} {
+		if _, err := ParseThreshold(s); err == nil {
+			t.Errorf("%q: expected error", s)
+		}
+	}
+}
+
+func TestThresholdCheck(t *testing.T) {
+	cases := []struct {
+		th       Threshold
+		count    uint
+		expected bool
+	}{
+		{AtLeast(5), 5, true},
+		{AtLeast(5), 4, false},
+		{AtMost(5), 5, true},
+		{AtMost(5), 6, false},
+		{AtMost(0), 0, true},
+		{Between(3, 4), 2, false},
+		{Between(3, 4), 3, true},
+		{Between(3, 4), 4, true},
+		{Between(3, 4), 5, false},
+		{Threshold{}, 0, true},
+	}
+	for _, c := range cases {
+		if c.th.Check(c.count) != c.expected {
+			t.Errorf("%v.Check(%d): expected %v", c.th, c.count, c.expected)
+		}
+	}
+}
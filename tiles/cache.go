@@ -0,0 +1,64 @@
+package tiles
+
+import (
+	"container/list"
+	"sync"
+)
+
+// Keeps the most recently used encoded tiles. Safe for concurrent use.
+type Cache struct {
+	size int
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List // Most recently used first
+}
+
+type cacheEntry struct {
+	key  string
+	data []byte
+}
+
+// Creates a cache holding up to size tiles
+func NewCache(size int) *Cache {
+	return &Cache{size: size, entries: make(map[string]*list.Element), order: list.New()}
+}
+
+// Returns a cached tile, marking it as recently used
+func (c *Cache) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(e)
+	return e.Value.(*cacheEntry).data, true
+}
+
+// Adds a tile, evicting the least recently used if the cache is full
+func (c *Cache) Add(key string, data []byte) {
+	if c.size <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[key]; ok {
+		e.Value.(*cacheEntry).data = data
+		c.order.MoveToFront(e)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&cacheEntry{key, data})
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+// Returns the number of cached tiles
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
@@ -0,0 +1,264 @@
+package tiles
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/png"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/vktec/slimy"
+	"github.com/vktec/slimy/cpu"
+)
+
+// Serves tiles over HTTP, with these paths:
+//
+//	/{seed}/{z}/{x}/{y}.png          slime chunks in the world with the given seed
+//	/{seed}/overlay/{z}/{x}/{y}.png  search results for the seed, if an overlay has been set for it
+//	/                                a simple map viewer, which takes the seed as a query parameter
+type Server struct {
+	Edition     slimy.Edition
+	WorkerCount int   // The number of goroutines drawing each tile
+	DefaultSeed int64 // The seed the viewer shows if none is given
+
+	cache *Cache
+
+	mu         sync.RWMutex
+	overlays   map[int64]overlayEntry
+	overlayGen int // Incremented by every SetOverlay
+}
+
+// An overlay, along with the generation it was set in.
+// The generation is part of the overlay's cache keys, so replacing an overlay also replaces its cached tiles.
+type overlayEntry struct {
+	*Overlay
+	gen int
+}
+
+// Creates a server for worlds of the given edition, caching up to cacheSize tiles
+func NewServer(edition slimy.Edition, workerCount, cacheSize int) *Server {
+	return &Server{Edition: edition, WorkerCount: workerCount, cache: NewCache(cacheSize), overlays: make(map[int64]overlayEntry)}
+}
+
+// Sets the overlay served for a seed, replacing any previous one
+func (s *Server) SetOverlay(seed int64, o *Overlay) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.overlayGen++
+	s.overlays[s.worldSeed(seed)] = overlayEntry{o, s.overlayGen}
+}
+
+// Bedrock slime chunks don't depend on the seed, so every seed shares the same tiles
+func (s *Server) worldSeed(seed int64) int64 {
+	if s.Edition == slimy.BedrockEdition {
+		return 0
+	}
+	return seed
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if r.URL.Path == "/" {
+		s.serveViewer(w)
+		return
+	}
+
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	overlay := len(parts) == 5 && parts[1] == "overlay"
+	if overlay {
+		parts = append(parts[:1], parts[2:]...)
+	}
+	if len(parts) != 4 || !strings.HasSuffix(parts[3], ".png") {
+		http.NotFound(w, r)
+		return
+	}
+	seed, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	zoom, errZ := strconv.Atoi(parts[1])
+	x, errX := strconv.ParseInt(parts[2], 10, 64)
+	y, errY := strconv.ParseInt(strings.TrimSuffix(parts[3], ".png"), 10, 64)
+	if errZ != nil || errX != nil || errY != nil {
+		http.NotFound(w, r)
+		return
+	}
+	seed = s.worldSeed(seed)
+
+	key := fmt.Sprintf("slime/%d/%d/%d/%d", seed, zoom, x, y)
+	var o overlayEntry
+	if overlay {
+		s.mu.RLock()
+		o = s.overlays[seed]
+		s.mu.RUnlock()
+		if o.Overlay == nil {
+			http.NotFound(w, r)
+			return
+		}
+		key = fmt.Sprintf("overlay/%d/%d/%d/%d/%d", seed, o.gen, zoom, x, y)
+	}
+
+	data, ok := s.cache.Get(key)
+	if !ok {
+		var img image.Image
+		if overlay {
+			img, ok = o.RenderTile(zoom, x, y)
+		} else {
+			img, ok = RenderTile(cpu.World{Seed: seed, Edition: s.Edition}, zoom, x, y, s.WorkerCount)
+		}
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, img); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		data = buf.Bytes()
+		s.cache.Add(key, data)
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	// Slime chunk tiles never change, but overlays can be replaced
+	if !overlay {
+		w.Header().Set("Cache-Control", "public, max-age=86400")
+	}
+	w.Write(data)
+}
+
+func (s *Server) serveViewer(w http.ResponseWriter) {
+	s.mu.RLock()
+	seeds := make([]string, 0, len(s.overlays))
+	for seed := range s.overlays {
+		seeds = append(seeds, strconv.Quote(strconv.FormatInt(seed, 10)))
+	}
+	s.mu.RUnlock()
+	// The viewer's seed is chosen in the browser, so it needs to know when every seed has the same tiles
+	sharedSeed := "null"
+	if s.Edition == slimy.BedrockEdition {
+		sharedSeed = strconv.Quote(strconv.FormatInt(s.worldSeed(0), 10))
+	}
+
+	page := strings.NewReplacer(
+		"MIN_ZOOM", strconv.Itoa(MinZoom),
+		"MAX_ZOOM", strconv.Itoa(MaxZoom),
+		"CHUNK_ZOOM", strconv.Itoa(ChunkZoom),
+		"TILE_SIZE", strconv.Itoa(TileSize),
+		"DEFAULT_SEED", strconv.Quote(strconv.FormatInt(s.DefaultSeed, 10)),
+		"OVERLAY_SEEDS", "["+strings.Join(seeds, ",")+"]",
+		"SHARED_SEED", sharedSeed,
+	).Replace(viewerPage)
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(page))
+}
+
+// A map viewer that needs nothing but this server.
+// Drag to move, scroll to zoom, and the seed and view are kept in the URL.
+const viewerPage = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Slime chunks</title>
+<style>
+html, body { margin: 0; height: 100%; overflow: hidden; background: #000; }
+#map { position: absolute; left: 0; top: 0; right: 0; bottom: 0; cursor: move; }
+#map img { position: absolute; width: TILE_SIZEpx; height: TILE_SIZEpx; image-rendering: pixelated; user-select: none; -webkit-user-drag: none; }
+#info { position: absolute; left: 8px; bottom: 8px; padding: 4px 8px; color: #fff; background: rgba(0, 0, 0, 0.7); font: 13px monospace; }
+</style>
+</head>
+<body>
+<div id="map"></div>
+<div id="info"></div>
+<script>
+"use strict";
+const minZoom = MIN_ZOOM, maxZoom = MAX_ZOOM, chunkZoom = CHUNK_ZOOM, tileSize = TILE_SIZE;
+const overlaySeeds = OVERLAY_SEEDS;
+const params = new URLSearchParams(location.search);
+const seed = params.get("seed") || DEFAULT_SEED;
+// The seed the server knows the world by, which is the same for every seed in Bedrock Edition
+const worldSeed = SHARED_SEED || seed;
+// The view is centred on chunk x, z
+let zoom = Math.min(maxZoom, Math.max(minZoom, parseInt(params.get("zoom") || chunkZoom, 10)));
+let x = parseFloat(params.get("x") || 0), z = parseFloat(params.get("z") || 0);
+const map = document.getElementById("map"), info = document.getElementById("info");
+let tiles = new Map();
+
+const scale = () => Math.pow(2, zoom - chunkZoom); // Pixels per chunk
+
+function draw() {
+	const s = scale(), span = tileSize / s;
+	const w = map.clientWidth, h = map.clientHeight;
+	const left = x - w / 2 / s, top = z - h / 2 / s;
+	const wanted = new Map();
+	for (let ty = Math.floor(top / span); ty * span < top + h / s; ty++) {
+		for (let tx = Math.floor(left / span); tx * span < left + w / s; tx++) {
+			const layers = [worldSeed + "/" + zoom + "/" + tx + "/" + ty];
+			if (overlaySeeds.includes(worldSeed)) layers.push(worldSeed + "/overlay/" + zoom + "/" + tx + "/" + ty);
+			for (const path of layers) {
+				let img = tiles.get(path);
+				if (!img) {
+					img = document.createElement("img");
+					img.src = "/" + path + ".png";
+					img.onerror = () => { img.style.visibility = "hidden"; };
+					map.appendChild(img);
+				}
+				img.style.left = Math.round((tx * span - left) * s) + "px";
+				img.style.top = Math.round((ty * span - top) * s) + "px";
+				wanted.set(path, img);
+			}
+		}
+	}
+	for (const [path, img] of tiles) {
+		if (!wanted.has(path)) map.removeChild(img);
+	}
+	tiles = wanted;
+	history.replaceState(null, "", "?seed=" + encodeURIComponent(seed) + "&zoom=" + zoom + "&x=" + Math.round(x) + "&z=" + Math.round(z));
+}
+
+function chunkAt(e) {
+	const s = scale();
+	return [Math.floor(x + (e.clientX - map.clientWidth / 2) / s), Math.floor(z + (e.clientY - map.clientHeight / 2) / s)];
+}
+
+let drag = null;
+map.addEventListener("mousedown", e => { drag = [e.clientX, e.clientY]; });
+addEventListener("mouseup", () => { drag = null; });
+addEventListener("mousemove", e => {
+	if (drag) {
+		x -= (e.clientX - drag[0]) / scale();
+		z -= (e.clientY - drag[1]) / scale();
+		drag = [e.clientX, e.clientY];
+		draw();
+	}
+	const [cx, cz] = chunkAt(e);
+	info.textContent = "Seed " + seed + ", chunk " + cx + ", " + cz + " (block " + cx * 16 + ", " + cz * 16 + ")";
+});
+map.addEventListener("wheel", e => {
+	e.preventDefault();
+	const next = Math.min(maxZoom, Math.max(minZoom, zoom - Math.sign(e.deltaY)));
+	if (next === zoom) return;
+	// Keep the chunk under the pointer in place
+	const [cx, cz] = chunkAt(e), before = scale();
+	zoom = next;
+	const after = scale();
+	x = cx + (x - cx) * before / after;
+	z = cz + (z - cz) * before / after;
+	for (const img of tiles.values()) map.removeChild(img);
+	tiles = new Map();
+	draw();
+}, { passive: false });
+addEventListener("resize", draw);
+draw();
+</script>
+</body>
+</html>
+`
@@ -0,0 +1,207 @@
+// Package tiles draws slime chunks as map tiles, and serves them over HTTP for web map viewers.
+//
+// Tiles are TileSize pixels square and addressed by zoom level and tile coordinates, as in slippy maps.
+// Tile coordinates may be negative, since they cover the whole world around the origin, with X increasing east and Y increasing south.
+// At ChunkZoom each pixel is one chunk, and each zoom level in or out doubles or halves the scale.
+package tiles
+
+import (
+	"image"
+	"image/color"
+	"runtime"
+	"sync"
+
+	"github.com/vktec/slimy"
+	"github.com/vktec/slimy/cpu"
+	"github.com/vktec/slimy/util"
+)
+
+const (
+	TileSize  = 256
+	MinZoom   = 0
+	ChunkZoom = 4 // The zoom level at which each pixel is one chunk
+	MaxZoom   = 8
+
+	// The fraction of slime chunks at which a zoomed out pixel is drawn in the slime chunk colour, twice the average
+	DensityScale = 0.2
+
+	// Tiles more than this many chunks from the origin aren't drawn, since their coordinates could overflow
+	maxChunk = 1 << 30
+)
+
+// The colours of overlay tiles
+var (
+	MaskColor   = color.NRGBA{255, 64, 64, 110}
+	ResultColor = color.NRGBA{255, 255, 0, 255}
+)
+
+// The chunks covered by a tile
+type tileArea struct {
+	x0, z0         int64 // The chunk at the tile's top left corner
+	chunksPerPixel int64 // More than 1 when zoomed out
+	pixelsPerChunk int64 // More than 1 when zoomed in
+}
+
+// Returns the area covered by a tile, or false if the tile doesn't exist
+func areaOf(zoom int, x, y int64) (tileArea, bool) {
+	if zoom < MinZoom || zoom > MaxZoom {
+		return tileArea{}, false
+	}
+	a := tileArea{chunksPerPixel: 1, pixelsPerChunk: 1}
+	if zoom < ChunkZoom {
+		a.chunksPerPixel = 1 << (ChunkZoom - zoom)
+	} else {
+		a.pixelsPerChunk = 1 << (zoom - ChunkZoom)
+	}
+	span := a.span()
+	if x < -maxChunk/span || x >= maxChunk/span || y < -maxChunk/span || y >= maxChunk/span {
+		return tileArea{}, false
+	}
+	a.x0, a.z0 = x*span, y*span
+	return a, true
+}
+
+// Returns the number of chunks across a tile
+func (a tileArea) span() int64 {
+	return TileSize * a.chunksPerPixel / a.pixelsPerChunk
+}
+
+// Returns the range of pixels covering a chunk, along one axis, given the tile's first chunk on that axis
+func (a tileArea) pixels(c, c0 int64) (p0, p1 int) {
+	d := c - c0
+	if a.pixelsPerChunk > 1 {
+		return int(d * a.pixelsPerChunk), int((d + 1) * a.pixelsPerChunk)
+	}
+	p := floorDiv(d, a.chunksPerPixel)
+	return int(p), int(p + 1)
+}
+
+// Draws a tile of slime chunks. When zoomed out, each pixel shows the density of slime chunks within it.
+func RenderTile(w cpu.World, zoom int, x, y int64, workerCount int) (*image.RGBA, bool) {
+	a, ok := areaOf(zoom, x, y)
+	if !ok {
+		return nil, false
+	}
+	if workerCount <= 0 {
+		workerCount = runtime.GOMAXPROCS(0)
+	}
+	background := color.RGBAModel.Convert(cpu.DefaultBackgroundColor).(color.RGBA)
+	slime := color.RGBAModel.Convert(cpu.DefaultSlimeChunkColor).(color.RGBA)
+
+	// Each cell is a pixel when zoomed out, or a chunk when zoomed in
+	cells := int(TileSize / a.pixelsPerChunk)
+	cpp := a.chunksPerPixel
+	img := image.NewRGBA(image.Rect(0, 0, TileSize, TileSize))
+	rowCh := make(chan int, cells)
+	for row := 0; row < cells; row++ {
+		rowCh <- row
+	}
+	close(rowCh)
+
+	wgroup := new(sync.WaitGroup)
+	wgroup.Add(workerCount)
+	for i := 0; i < workerCount; i++ {
+		go func() {
+			defer wgroup.Done()
+			for row := range rowCh {
+				cz := a.z0 + int64(row)*cpp
+				for col := 0; col < cells; col++ {
+					cx := a.x0 + int64(col)*cpp
+					count := 0
+					for dz := int64(0); dz < cpp; dz++ {
+						for dx := int64(0); dx < cpp; dx++ {
+							if w.CalcChunk(int32(cx+dx), int32(cz+dz)) {
+								count++
+							}
+						}
+					}
+
+					c := lerp(background, slime, float64(count)/float64(cpp*cpp)/DensityScale)
+					// Rows are split between workers, so each pixel is only written by one
+					ppc := int(a.pixelsPerChunk)
+					for py := row * ppc; py < (row+1)*ppc; py++ {
+						for px := col * ppc; px < (col+1)*ppc; px++ {
+							img.SetRGBA(px, py, c)
+						}
+					}
+				}
+			}
+		}()
+	}
+	wgroup.Wait()
+	return img, true
+}
+
+// Mixes two colours, with t from 0 for a to 1 for b. Values of t above 1 are treated as 1.
+func lerp(a, b color.RGBA, t float64) color.RGBA {
+	if t > 1 {
+		t = 1
+	}
+	mix := func(a, b uint8) uint8 { return uint8(float64(a) + (float64(b)-float64(a))*t + 0.5) }
+	return color.RGBA{mix(a.R, b.R), mix(a.G, b.G), mix(a.B, b.B), mix(a.A, b.A)}
+}
+
+// Search results to draw over slime chunk tiles, along with the chunks covered by their masks
+type Overlay struct {
+	results []slimy.Result
+	offsets []image.Point
+	reach   image.Rectangle // The bounds of the offsets
+}
+
+// Creates an overlay of results found with the given mask
+func NewOverlay(results []slimy.Result, mask image.Image, weighted bool) *Overlay {
+	o := &Overlay{results: results, offsets: util.MaskOffsets(mask, weighted)}
+	for _, off := range o.offsets {
+		o.reach = o.reach.Union(image.Rectangle{off, off.Add(image.Pt(1, 1))})
+	}
+	return o
+}
+
+// Draws a transparent tile showing the masks of results in MaskColor, and the results themselves in ResultColor
+func (o *Overlay) RenderTile(zoom int, x, y int64) (*image.NRGBA, bool) {
+	a, ok := areaOf(zoom, x, y)
+	if !ok {
+		return nil, false
+	}
+	img := image.NewNRGBA(image.Rect(0, 0, TileSize, TileSize))
+	span := a.span()
+	tileRect := image.Rect(0, 0, int(span), int(span))
+	fill := func(cx, cz int64, c color.NRGBA) {
+		px0, px1 := a.pixels(cx, a.x0)
+		py0, py1 := a.pixels(cz, a.z0)
+		for py := py0; py < py1; py++ {
+			for px := px0; px < px1; px++ {
+				img.SetNRGBA(px, py, c)
+			}
+		}
+	}
+
+	var visible []slimy.Result
+	for _, r := range o.results {
+		// Positions relative to the tile fit in an int, since tiles are near enough the origin
+		p := image.Pt(int(int64(r.X)-a.x0), int(int64(r.Z)-a.z0))
+		if o.reach.Add(p).Overlaps(tileRect) {
+			visible = append(visible, r)
+			for _, off := range o.offsets {
+				if off.Add(p).In(tileRect) {
+					fill(int64(r.X)+int64(off.X), int64(r.Z)+int64(off.Y), MaskColor)
+				}
+			}
+		}
+	}
+	// Results go on top of every mask
+	for _, r := range visible {
+		if image.Pt(int(int64(r.X)-a.x0), int(int64(r.Z)-a.z0)).In(tileRect) {
+			fill(int64(r.X), int64(r.Z), ResultColor)
+		}
+	}
+	return img, true
+}
+
+func floorDiv(a, b int64) int64 {
+	q := a / b
+	if a%b != 0 && (a < 0) != (b < 0) {
+		q--
+	}
+	return q
+}
@@ -0,0 +1,222 @@
+package tiles
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/vktec/slimy"
+	"github.com/vktec/slimy/cpu"
+	"github.com/vktec/slimy/util"
+)
+
+func TestRenderTileChunks(t *testing.T) {
+	world := cpu.JavaWorld(7)
+	// At MaxZoom each chunk is 16 pixels, so a tile covers 16 chunks
+	img, ok := RenderTile(world, MaxZoom, -3, 2, 4)
+	if !ok {
+		t.Fatal("Tile not rendered")
+	}
+	for z := int32(0); z < 16; z++ {
+		for x := int32(0); x < 16; x++ {
+			expected := cpu.DefaultBackgroundColor
+			if world.CalcChunk(-48+x, 32+z) {
+				expected = cpu.DefaultSlimeChunkColor
+			}
+			er, eg, eb, ea := expected.RGBA()
+			for _, p := range []image.Point{{int(x) * 16, int(z) * 16}, {int(x)*16 + 15, int(z)*16 + 15}} {
+				r, g, b, a := img.At(p.X, p.Y).RGBA()
+				if r != er || g != eg || b != eb || a != ea {
+					t.Fatalf("Chunk %d, %d is drawn wrongly at %v", -48+x, 32+z, p)
+				}
+			}
+		}
+	}
+}
+
+func TestRenderTileDensity(t *testing.T) {
+	world := cpu.JavaWorld(7)
+	img, ok := RenderTile(world, MinZoom, -1, 0, 4)
+	if !ok {
+		t.Fatal("Tile not rendered")
+	}
+	// At MinZoom each pixel is 16x16 chunks, so the tile starts at chunk -4096, 0
+	const cpp = 16
+	px, py := 100, 37
+	count := 0
+	for z := int32(0); z < cpp; z++ {
+		for x := int32(0); x < cpp; x++ {
+			if world.CalcChunk(-4096+int32(px)*cpp+x, int32(py)*cpp+z) {
+				count++
+			}
+		}
+	}
+	background := color.RGBAModel.Convert(cpu.DefaultBackgroundColor).(color.RGBA)
+	slime := color.RGBAModel.Convert(cpu.DefaultSlimeChunkColor).(color.RGBA)
+	expected := lerp(background, slime, float64(count)/cpp/cpp/DensityScale)
+	if c := img.RGBAAt(px, py); c != expected {
+		t.Errorf("Pixel with %d slime chunks is %v, expected %v", count, c, expected)
+	}
+}
+
+func TestRenderTileInvalid(t *testing.T) {
+	world := cpu.BedrockWorld()
+	for _, tile := range []struct {
+		zoom int
+		x, y int64
+	}{
+		{MinZoom - 1, 0, 0},
+		{MaxZoom + 1, 0, 0},
+		{MaxZoom, 1 << 40, 0},
+		{MinZoom, 0, -(1 << 40)},
+	} {
+		if _, ok := RenderTile(world, tile.zoom, tile.x, tile.y, 1); ok {
+			t.Errorf("Tile %v rendered", tile)
+		}
+	}
+}
+
+func TestOverlay(t *testing.T) {
+	results := []slimy.Result{{X: 10, Z: -5, Count: 3, Score: 3}}
+	o := NewOverlay(results, util.GenDonut(1, 3), false)
+	// At ChunkZoom each pixel is one chunk, so tile 0, -1 covers chunks 0-255, -256 to -1
+	img, ok := o.RenderTile(ChunkZoom, 0, -1)
+	if !ok {
+		t.Fatal("Tile not rendered")
+	}
+	if c := img.NRGBAAt(10, 256-5); c != ResultColor {
+		t.Errorf("Result drawn as %v", c)
+	}
+	if c := img.NRGBAAt(12, 256-5); c != MaskColor {
+		t.Errorf("Mask drawn as %v", c)
+	}
+	if c := img.NRGBAAt(10+5, 256-5); c.A != 0 {
+		t.Errorf("Chunk outside mask drawn as %v", c)
+	}
+
+	img, _ = o.RenderTile(ChunkZoom, 1, 0)
+	for i, c := range img.Pix {
+		if c != 0 {
+			t.Fatalf("Tile without results has a pixel at %d", i/4)
+		}
+	}
+}
+
+func TestCache(t *testing.T) {
+	c := NewCache(2)
+	c.Add("a", []byte("a"))
+	c.Add("b", []byte("b"))
+	c.Get("a")
+	c.Add("c", []byte("c"))
+	if _, ok := c.Get("b"); ok {
+		t.Error("Least recently used tile not evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if data, ok := c.Get(key); !ok || string(data) != key {
+			t.Errorf("Tile %s not cached", key)
+		}
+	}
+	if c.Len() != 2 {
+		t.Errorf("Cache has %d tiles, expected 2", c.Len())
+	}
+
+	c = NewCache(0)
+	c.Add("a", []byte("a"))
+	if c.Len() != 0 {
+		t.Error("Tile cached in disabled cache")
+	}
+}
+
+func TestServer(t *testing.T) {
+	s := NewServer(slimy.JavaEdition, 2, 16)
+	s.SetOverlay(7, NewOverlay([]slimy.Result{{X: 0, Z: 0}}, util.GenDonut(1, 3), false))
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+
+	for _, test := range []struct {
+		path        string
+		status      int
+		contentType string
+	}{
+		{"/", http.StatusOK, "text/html; charset=utf-8"},
+		{"/7/4/0/0.png", http.StatusOK, "image/png"},
+		{"/7/4/0/0.png", http.StatusOK, "image/png"}, // Cached
+		{"/-7/0/-1/-1.png", http.StatusOK, "image/png"},
+		{"/7/overlay/4/0/0.png", http.StatusOK, "image/png"},
+		{"/8/overlay/4/0/0.png", http.StatusNotFound, ""},
+		{"/7/9/0/0.png", http.StatusNotFound, ""},
+		{"/7/4/0/0.jpg", http.StatusNotFound, ""},
+		{"/seed/4/0/0.png", http.StatusNotFound, ""},
+		{"/7/4/0.png", http.StatusNotFound, ""},
+	} {
+		resp, err := http.Get(srv.URL + test.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != test.status {
+			t.Errorf("%s: status %d, expected %d", test.path, resp.StatusCode, test.status)
+		} else if test.contentType != "" {
+			if ct := resp.Header.Get("Content-Type"); ct != test.contentType {
+				t.Errorf("%s: content type %q, expected %q", test.path, ct, test.contentType)
+			}
+			if test.contentType == "image/png" {
+				if _, err := png.Decode(resp.Body); err != nil {
+					t.Errorf("%s: %v", test.path, err)
+				}
+			}
+		}
+		resp.Body.Close()
+	}
+	if n := s.cache.Len(); n != 3 {
+		t.Errorf("%d tiles cached, expected 3", n)
+	}
+}
+
+func TestServerReplaceOverlay(t *testing.T) {
+	s := NewServer(slimy.JavaEdition, 2, 16)
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+	resultAt := func(x, y int) color.Color {
+		resp, err := http.Get(srv.URL + "/7/overlay/4/0/0.png")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		img, err := png.Decode(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return color.NRGBAModel.Convert(img.At(x, y))
+	}
+
+	s.SetOverlay(7, NewOverlay([]slimy.Result{{X: 20, Z: 20}}, util.GenDonut(1, 3), false))
+	if c := resultAt(20, 20); c != ResultColor {
+		t.Errorf("Result drawn as %v", c)
+	}
+	// The old overlay's tile is cached, but mustn't be served once it is replaced
+	s.SetOverlay(7, NewOverlay([]slimy.Result{{X: 100, Z: 100}}, util.GenDonut(1, 3), false))
+	if c := resultAt(20, 20); c.(color.NRGBA).A != 0 {
+		t.Errorf("Replaced result still drawn as %v", c)
+	}
+	if c := resultAt(100, 100); c != ResultColor {
+		t.Errorf("New result drawn as %v", c)
+	}
+}
+
+func TestViewerBedrock(t *testing.T) {
+	s := NewServer(slimy.BedrockEdition, 1, 0)
+	s.DefaultSeed = 123
+	s.SetOverlay(123, NewOverlay(nil, util.GenDonut(1, 3), false))
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	// Bedrock tiles and overlays are requested by the shared seed, whichever seed the viewer shows
+	for _, expected := range []string{`const overlaySeeds = ["0"];`, `const worldSeed = "0" || seed;`, `|| "123";`} {
+		if !strings.Contains(rec.Body.String(), expected) {
+			t.Errorf("Viewer doesn't contain %s", expected)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package util
+
+import (
+	"image"
+	"image/color"
+)
+
+// Reports whether a pixel of a mask image is part of the mask.
+// A pixel is included if it is bright and mostly opaque.
+func InMask(c color.Color) bool {
+	r, g, b, a := c.RGBA()
+	return (r > 0x7fff || g > 0x7fff || b > 0x7fff) && a > 0x7fff
+}
+
+// The largest weight a pixel of a weighted mask can have
+const MaxMaskWeight = 255
+
+// Returns the weight of a pixel of a weighted mask image, from 0 to MaxMaskWeight.
+// The weight is the pixel's brightest channel, scaled by its opacity.
+func MaskWeight(c color.Color) uint8 {
+	r, g, b, _ := c.RGBA() // Already scaled by alpha
+	if g > r {
+		r = g
+	}
+	if b > r {
+		r = b
+	}
+	return uint8(r >> 8)
+}
+
+// Returns the positions of a mask image's cells relative to its centre, which is where search results are reported.
+// If weighted is true, every cell with a weight is included, as with a weighted search.
+func MaskOffsets(mask image.Image, weighted bool) []image.Point {
+	dim := mask.Bounds().Canon()
+	cx, cz := dim.Min.X+dim.Dx()/2, dim.Min.Y+dim.Dy()/2
+	var offsets []image.Point
+	for y := dim.Min.Y; y < dim.Max.Y; y++ {
+		for x := dim.Min.X; x < dim.Max.X; x++ {
+			c := mask.At(x, y)
+			if weighted && MaskWeight(c) > 0 || !weighted && InMask(c) {
+				offsets = append(offsets, image.Pt(x-cx, y-cz))
+			}
+		}
+	}
+	return offsets
+}
@@ -0,0 +1,499 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// The largest width or height of a mask described by a spec, the same as cpu.MaxMaskSize
+const maxSpecSize = 8192
+
+// A set of cells, relative to the centre of the mask
+type maskShape interface {
+	// Returns a rectangle containing every cell in the shape
+	bounds() image.Rectangle
+	contains(x, z int) bool
+}
+
+type circleShape struct{ r float64 }
+
+func (s circleShape) bounds() image.Rectangle {
+	r := int(s.r)
+	return image.Rect(-r, -r, r+1, r+1)
+}
+func (s circleShape) contains(x, z int) bool {
+	return float64(x*x+z*z) <= s.r*s.r
+}
+
+type annulusShape struct{ inner, outer float64 }
+
+func (s annulusShape) bounds() image.Rectangle {
+	return circleShape{s.outer}.bounds()
+}
+func (s annulusShape) contains(x, z int) bool {
+	d := float64(x*x + z*z)
+	return s.inner*s.inner < d && d <= s.outer*s.outer
+}
+
+type rectShape struct{ r image.Rectangle }
+
+func (s rectShape) bounds() image.Rectangle {
+	return s.r
+}
+func (s rectShape) contains(x, z int) bool {
+	return image.Pt(x, z).In(s.r)
+}
+
+type polygonShape struct{ points []image.Point }
+
+func (s polygonShape) bounds() image.Rectangle {
+	r := image.Rectangle{s.points[0], s.points[0].Add(image.Pt(1, 1))}
+	for _, p := range s.points[1:] {
+		r = r.Union(image.Rectangle{p, p.Add(image.Pt(1, 1))})
+	}
+	return r
+}
+
+// Cells whose centres are inside the polygon or on its edges are included
+func (s polygonShape) contains(x, z int) bool {
+	inside := false
+	for i, a := range s.points {
+		b := s.points[(i+1)%len(s.points)]
+		// On the edge from a to b
+		cross := (b.X-a.X)*(z-a.Y) - (b.Y-a.Y)*(x-a.X)
+		if cross == 0 && minInt(a.X, b.X) <= x && x <= maxInt(a.X, b.X) && minInt(a.Y, b.Y) <= z && z <= maxInt(a.Y, b.Y) {
+			return true
+		}
+		// Crosses a ray from the cell towards +x
+		if (a.Y > z) != (b.Y > z) {
+			ix := float64(a.X) + float64(z-a.Y)*float64(b.X-a.X)/float64(b.Y-a.Y)
+			if float64(x) < ix {
+				inside = !inside
+			}
+		}
+	}
+	return inside
+}
+
+type unionShape []maskShape
+
+func (s unionShape) bounds() (r image.Rectangle) {
+	for _, sub := range s {
+		r = r.Union(sub.bounds())
+	}
+	return r
+}
+func (s unionShape) contains(x, z int) bool {
+	for _, sub := range s {
+		if sub.contains(x, z) {
+			return true
+		}
+	}
+	return false
+}
+
+// The first shape, without any of the others
+type diffShape []maskShape
+
+func (s diffShape) bounds() image.Rectangle {
+	return s[0].bounds()
+}
+func (s diffShape) contains(x, z int) bool {
+	if !s[0].contains(x, z) {
+		return false
+	}
+	for _, sub := range s[1:] {
+		if sub.contains(x, z) {
+			return false
+		}
+	}
+	return true
+}
+
+type translateShape struct {
+	d     image.Point
+	shape maskShape
+}
+
+func (s translateShape) bounds() image.Rectangle {
+	return s.shape.bounds().Add(s.d)
+}
+func (s translateShape) contains(x, z int) bool {
+	return s.shape.contains(x-s.d.X, z-s.d.Y)
+}
+
+// Rows of cells, centred the same way as a mask image
+type asciiShape struct {
+	rows []string
+}
+
+func (s asciiShape) bounds() image.Rectangle {
+	w, h := len(s.rows[0]), len(s.rows)
+	return image.Rect(-w/2, -h/2, w-w/2, h-h/2)
+}
+func (s asciiShape) contains(x, z int) bool {
+	w, h := len(s.rows[0]), len(s.rows)
+	x, z = x+w/2, z+h/2
+	return 0 <= z && z < h && 0 <= x && x < w && s.rows[z][x] == '#'
+}
+
+// Parses a textual mask description into a mask image.
+// Coordinates are in chunks relative to the mask centre, which the image is centred on.
+// A spec is one of the following shapes:
+//
+//	circle(r)                  cells within r of the centre
+//	annulus(inner, outer)      cells more than inner and at most outer from the centre
+//	rect(x0, z0, x1, z1)       cells between two corners, inclusive
+//	polygon(x, z, x, z, ...)   cells inside or on the edges of a polygon
+//	ascii("..#../.###./..#..") rows of cells separated by /, where # is in the mask and . is not
+//	union(a, b, ...)           cells in any of the shapes
+//	diff(a, b, ...)            cells in a but none of the others
+//	translate(dx, dz, a)       a, moved away from the centre
+func ParseMask(spec string) (image.Image, error) {
+	p := &specParser{src: spec}
+	shape, err := p.shape()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if p.pos < len(p.src) {
+		return nil, p.errorf("unexpected %q after mask", p.src[p.pos:])
+	}
+	return renderShape(shape)
+}
+
+func renderShape(shape maskShape) (image.Image, error) {
+	// Find the cells actually in the shape, since differences can leave parts of the bounds empty
+	var cells image.Rectangle
+	b := shape.bounds()
+	if b.Dx() > 2*maxSpecSize || b.Dy() > 2*maxSpecSize {
+		return nil, errors.New("Mask is too large")
+	}
+	for z := b.Min.Y; z < b.Max.Y; z++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if shape.contains(x, z) {
+				cells = cells.Union(image.Rect(x, z, x+1, z+1))
+			}
+		}
+	}
+	if cells.Empty() {
+		return nil, errors.New("Mask contains no cells")
+	}
+
+	// Results are reported at the centre of the image, so keep the origin there
+	rx := maxInt(-cells.Min.X, cells.Max.X-1)
+	rz := maxInt(-cells.Min.Y, cells.Max.Y-1)
+	if 2*rx+1 > maxSpecSize || 2*rz+1 > maxSpecSize {
+		return nil, errors.New("Mask is too large")
+	}
+	img := image.NewAlpha(image.Rect(-rx, -rz, rx+1, rz+1))
+	for z := cells.Min.Y; z < cells.Max.Y; z++ {
+		for x := cells.Min.X; x < cells.Max.X; x++ {
+			if shape.contains(x, z) {
+				img.SetAlpha(x, z, color.Alpha{255})
+			}
+		}
+	}
+	return img, nil
+}
+
+type specParser struct {
+	src string
+	pos int
+}
+
+func (p *specParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("Mask spec: at position %d: %s", p.pos+1, fmt.Sprintf(format, args...))
+}
+
+func (p *specParser) skipSpace() {
+	for p.pos < len(p.src) && unicode.IsSpace(rune(p.src[p.pos])) {
+		p.pos++
+	}
+}
+
+// Consumes c, if it is the next non-space character
+func (p *specParser) accept(c byte) bool {
+	p.skipSpace()
+	if p.pos < len(p.src) && p.src[p.pos] == c {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// Reports that something other than what was wanted was found
+func (p *specParser) unexpected(wanted string) error {
+	if p.pos >= len(p.src) {
+		return p.errorf("expected %s, found end of spec", wanted)
+	}
+	return p.errorf("expected %s, found %q", wanted, p.src[p.pos])
+}
+
+// An argument to a shape: one of a number, a string or a shape
+type specArg struct {
+	pos   int
+	num   float64
+	str   *string
+	shape maskShape
+}
+
+func (p *specParser) shape() (maskShape, error) {
+	p.skipSpace()
+	start := p.pos
+	for p.pos < len(p.src) && unicode.IsLetter(rune(p.src[p.pos])) {
+		p.pos++
+	}
+	name := p.src[start:p.pos]
+	if name == "" {
+		p.pos = start
+		return nil, p.errorf("expected shape name")
+	}
+	if !p.accept('(') {
+		return nil, p.unexpected("'('")
+	}
+
+	var args []specArg
+	if !p.accept(')') {
+		for {
+			arg, err := p.arg()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+			if p.accept(')') {
+				break
+			}
+			if !p.accept(',') {
+				return nil, p.unexpected("',' or ')'")
+			}
+		}
+	}
+
+	// Errors about the shape as a whole are reported at its start
+	end := p.pos
+	p.pos = start
+	shape, err := p.build(name, args)
+	if err != nil {
+		return nil, err
+	}
+	p.pos = end
+	return shape, nil
+}
+
+func (p *specParser) arg() (specArg, error) {
+	p.skipSpace()
+	arg := specArg{pos: p.pos}
+	if p.pos >= len(p.src) {
+		return arg, p.errorf("unexpected end of spec")
+	}
+
+	switch c := p.src[p.pos]; {
+	case c == '"' || c == '\'':
+		end := strings.IndexByte(p.src[p.pos+1:], c)
+		if end < 0 {
+			return arg, p.errorf("unterminated string")
+		}
+		s := p.src[p.pos+1 : p.pos+1+end]
+		arg.str = &s
+		p.pos += end + 2
+	case unicode.IsLetter(rune(c)):
+		shape, err := p.shape()
+		if err != nil {
+			return arg, err
+		}
+		arg.shape = shape
+	default:
+		start := p.pos
+		for p.pos < len(p.src) && strings.IndexByte("+-.0123456789eE", p.src[p.pos]) >= 0 {
+			p.pos++
+		}
+		n, err := strconv.ParseFloat(p.src[start:p.pos], 64)
+		if err != nil || math.IsInf(n, 0) || math.IsNaN(n) {
+			p.pos = start
+			return arg, p.errorf("expected number, string or shape")
+		}
+		arg.num = n
+	}
+	return arg, nil
+}
+
+func (p *specParser) build(name string, args []specArg) (maskShape, error) {
+	// Checks the argument at index i and converts it to the wanted type
+	num := func(i int) (float64, error) {
+		if args[i].shape != nil || args[i].str != nil {
+			p.pos = args[i].pos
+			return 0, p.errorf("%s expects a number here", name)
+		}
+		return args[i].num, nil
+	}
+	radius := func(i int) (float64, error) {
+		n, err := num(i)
+		if err == nil && n < 0 {
+			p.pos = args[i].pos
+			err = p.errorf("%s radius must not be negative", name)
+		} else if err == nil && !(n <= maxSpecSize/2) {
+			// Larger radii would make masks too large anyway, and can overflow when converted to an int
+			p.pos = args[i].pos
+			err = p.errorf("%s radius must be at most %d", name, maxSpecSize/2)
+		}
+		return n, err
+	}
+	integer := func(i int) (int, error) {
+		n, err := num(i)
+		if err == nil && (n != math.Trunc(n) || math.Abs(n) > maxSpecSize) {
+			p.pos = args[i].pos
+			err = p.errorf("%s expects an integer from %d to %d here", name, -maxSpecSize, maxSpecSize)
+		}
+		return int(n), err
+	}
+	shapes := func(from int) ([]maskShape, error) {
+		var s []maskShape
+		for i := from; i < len(args); i++ {
+			if args[i].shape == nil {
+				p.pos = args[i].pos
+				return nil, p.errorf("%s expects a shape here", name)
+			}
+			s = append(s, args[i].shape)
+		}
+		return s, nil
+	}
+	argCount := func(n int) error {
+		if len(args) != n {
+			return p.errorf("%s takes %d arguments, not %d", name, n, len(args))
+		}
+		return nil
+	}
+
+	switch name {
+	case "circle":
+		if err := argCount(1); err != nil {
+			return nil, err
+		}
+		r, err := radius(0)
+		return circleShape{r}, err
+
+	case "annulus":
+		if err := argCount(2); err != nil {
+			return nil, err
+		}
+		inner, err := radius(0)
+		if err != nil {
+			return nil, err
+		}
+		outer, err := radius(1)
+		return annulusShape{inner, outer}, err
+
+	case "rect":
+		if err := argCount(4); err != nil {
+			return nil, err
+		}
+		var c [4]int
+		for i := range c {
+			var err error
+			if c[i], err = integer(i); err != nil {
+				return nil, err
+			}
+		}
+		r := image.Rect(c[0], c[1], c[2], c[3])
+		r.Max = r.Max.Add(image.Pt(1, 1)) // The corners are inclusive
+		return rectShape{r}, nil
+
+	case "polygon":
+		if len(args) < 6 || len(args)%2 != 0 {
+			return nil, p.errorf("polygon takes at least 3 points, as pairs of coordinates")
+		}
+		points := make([]image.Point, len(args)/2)
+		for i := range points {
+			x, err := integer(2 * i)
+			if err != nil {
+				return nil, err
+			}
+			z, err := integer(2*i + 1)
+			if err != nil {
+				return nil, err
+			}
+			points[i] = image.Pt(x, z)
+		}
+		return polygonShape{points}, nil
+
+	case "ascii":
+		if err := argCount(1); err != nil {
+			return nil, err
+		}
+		if args[0].str == nil {
+			p.pos = args[0].pos
+			return nil, p.errorf("ascii expects a string")
+		}
+		rows := strings.Split(*args[0].str, "/")
+		for i, row := range rows {
+			rows[i] = strings.TrimSpace(row)
+			if len(rows[i]) != len(rows[0]) {
+				return nil, p.errorf("ascii rows must all be the same length")
+			}
+			if strings.Trim(rows[i], "#.") != "" {
+				return nil, p.errorf("ascii rows may only contain # and .")
+			}
+		}
+		if len(rows[0]) == 0 {
+			return nil, p.errorf("ascii must not be empty")
+		}
+		if len(rows[0]) > maxSpecSize || len(rows) > maxSpecSize {
+			return nil, p.errorf("ascii is too large")
+		}
+		return asciiShape{rows}, nil
+
+	case "union", "diff":
+		if len(args) == 0 {
+			return nil, p.errorf("%s takes at least one shape", name)
+		}
+		s, err := shapes(0)
+		if err != nil {
+			return nil, err
+		}
+		if name == "union" {
+			return unionShape(s), nil
+		}
+		return diffShape(s), nil
+
+	case "translate":
+		if err := argCount(3); err != nil {
+			return nil, err
+		}
+		dx, err := integer(0)
+		if err != nil {
+			return nil, err
+		}
+		dz, err := integer(1)
+		if err != nil {
+			return nil, err
+		}
+		s, err := shapes(2)
+		if err != nil {
+			return nil, err
+		}
+		return translateShape{image.Pt(dx, dz), s[0]}, nil
+
+	default:
+		return nil, p.errorf("unknown shape %q", name)
+	}
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
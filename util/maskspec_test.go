@@ -0,0 +1,105 @@
+package util
+
+import (
+	"fmt"
+	"image"
+	"strings"
+	"testing"
+)
+
+// Draws a mask as rows of # and ., separated by /
+func maskString(img image.Image) string {
+	b := img.Bounds()
+	var rows []string
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		row := ""
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if InMask(img.At(x, y)) {
+				row += "#"
+			} else {
+				row += "."
+			}
+		}
+		rows = append(rows, row)
+	}
+	return strings.Join(rows, "/")
+}
+
+func TestParseMaskDonut(t *testing.T) {
+	for _, r := range [][2]int{{1, 8}, {0, 3}, {5, 12}} {
+		img, err := ParseMask(fmt.Sprintf("annulus(%d, %d)", r[0], r[1]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		donut := GenDonut(r[0], r[1])
+		if img.Bounds() != donut.Bounds() {
+			t.Fatalf("annulus(%d, %d): expected bounds %v, got %v", r[0], r[1], donut.Bounds(), img.Bounds())
+		}
+		if maskString(img) != maskString(donut) {
+			t.Fatalf("annulus(%d, %d) does not match GenDonut", r[0], r[1])
+		}
+	}
+}
+
+func TestParseMask(t *testing.T) {
+	cases := []struct {
+		spec, expected string
+	}{
+		{"circle(1)", ".#./###/.#."},
+		{"circle(1.5)", "###/###/###"},
+		{"rect(0, 0, 1, 0)", ".##"},
+		{"rect(1, -1, -1, 1)", "###/###/###"},
+		{"translate(2, 0, circle(0))", "....#"},
+		{"diff(rect(-1, -1, 1, 1), circle(0))", "###/#.#/###"},
+		{"union(rect(-1, 0, 1, 0), rect(0, -1, 0, 1))", ".#./###/.#."},
+		{" ascii( '#.#/.#./#.#' ) ", "#.#/.#./#.#"},
+		{`ascii("##")`, "##."},
+		{"polygon(-2, -2, 2, -2, 0, 2)", "#####/.###./.###./..#../..#.."},
+		{"diff(circle(3), circle(2), rect(-3, 0, 3, 3))", "..#../##.##/#...#/...../...../...../....."},
+	}
+	for _, c := range cases {
+		img, err := ParseMask(c.spec)
+		if err != nil {
+			t.Errorf("%s: %v", c.spec, err)
+			continue
+		}
+		if s := maskString(img); s != c.expected {
+			t.Errorf("%s: expected %s, got %s", c.spec, c.expected, s)
+		}
+	}
+}
+
+func TestParseMaskErrors(t *testing.T) {
+	cases := []struct {
+		spec, err string
+	}{
+		{"", "at position 1: expected shape name"},
+		{"square(3)", `at position 1: unknown shape "square"`},
+		{"circle(3", "at position 9: expected ',' or ')', found end of spec"},
+		{"circle 3", `at position 8: expected '(', found '3'`},
+		{"circle(3) x", `at position 11: unexpected "x" after mask`},
+		{"circle(-1)", "at position 8: circle radius must not be negative"},
+		{"circle(1, 2)", "circle takes 1 arguments, not 2"},
+		{"rect(0, 0, 1.5, 2)", "at position 12: rect expects an integer"},
+		{"union(circle(1), 3)", "at position 18: union expects a shape here"},
+		{"translate(1, 1, 'x')", "translate expects a shape here"},
+		{"ascii('#./#')", "ascii rows must all be the same length"},
+		{"ascii('#x')", "ascii rows may only contain # and ."},
+		{"ascii('abc", "unterminated string"},
+		{"polygon(0, 0, 1, 1)", "polygon takes at least 3 points"},
+		{"diff(circle(1), circle(2))", "Mask contains no cells"},
+		{"circle(4096)", "Mask is too large"},
+		{"circle(5000)", "at position 8: circle radius must be at most 4096"},
+		{"circle(1e19)", "at position 8: circle radius must be at most 4096"},
+		{"annulus(1, 1e300)", "at position 12: annulus radius must be at most 4096"},
+		{"translate(8192, 0, circle(0))", "Mask is too large"},
+	}
+	for _, c := range cases {
+		_, err := ParseMask(c.spec)
+		if err == nil {
+			t.Errorf("%q: expected error", c.spec)
+		} else if !strings.Contains(err.Error(), c.err) {
+			t.Errorf("%q: expected error containing %q, got %q", c.spec, c.err, err)
+		}
+	}
+}
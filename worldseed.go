@@ -0,0 +1,182 @@
+package slimy
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+
+	"github.com/vktec/slimy/nbt"
+)
+
+// Reads a Java Edition world's seed from a world folder, a level.dat file, or a server.properties file.
+//
+// A world folder is read from its level.dat, or its server.properties if it has no level.dat.
+// For a server.properties file, the level.dat of the world named by level-name is read if it exists,
+// since level-seed only applies when the world is created and may have been changed since.
+// Otherwise the level-seed property is parsed with ParseSeed.
+func ReadWorldSeed(name string) (int64, error) {
+	info, err := os.Stat(name)
+	if err != nil {
+		return 0, err
+	}
+	if info.IsDir() {
+		for _, file := range []string{"level.dat", "server.properties"} {
+			path := filepath.Join(name, file)
+			if _, err := os.Stat(path); err == nil {
+				return ReadWorldSeed(path)
+			}
+		}
+		return 0, fmt.Errorf("%s has no level.dat or server.properties", name)
+	}
+
+	f, err := os.Open(name)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	if filepath.Ext(name) == ".properties" {
+		return readPropertiesSeed(f, filepath.Dir(name))
+	}
+	seed, err := ReadLevelSeed(f)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", name, err)
+	}
+	return seed, nil
+}
+
+// Reads the world seed from the contents of a Java Edition level.dat file
+func ReadLevelSeed(r io.Reader) (int64, error) {
+	_, root, err := nbt.Read(r)
+	if err != nil {
+		return 0, err
+	}
+	// Since 1.16, the seed is part of the world generation settings
+	if seed, ok := nbt.Lookup(root, "Data", "WorldGenSettings", "seed").(int64); ok {
+		return seed, nil
+	}
+	if seed, ok := nbt.Lookup(root, "Data", "RandomSeed").(int64); ok {
+		return seed, nil
+	}
+	return 0, fmt.Errorf("No seed in level.dat. Bedrock Edition worlds don't need one")
+}
+
+// Reads the seed from a server.properties file, preferring the level.dat of its world, which is looked for in dir
+func readPropertiesSeed(r io.Reader, dir string) (int64, error) {
+	props, err := readProperties(r)
+	if err != nil {
+		return 0, err
+	}
+
+	levelName := props["level-name"]
+	if levelName == "" {
+		levelName = "world"
+	}
+	levelDat := filepath.Join(dir, levelName, "level.dat")
+	if _, err := os.Stat(levelDat); err == nil {
+		return ReadWorldSeed(levelDat)
+	}
+
+	if javaTrim(props["level-seed"]) == "" {
+		return 0, fmt.Errorf("server.properties has no level-seed, so the seed is random, and %s was not found", levelDat)
+	}
+	return ParseSeed(props["level-seed"])
+}
+
+// Reads a Java properties file, as written by the game for server.properties
+func readProperties(r io.Reader) (map[string]string, error) {
+	props := make(map[string]string)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimLeft(scanner.Text(), " \t\f")
+		if line == "" || line[0] == '#' || line[0] == '!' {
+			continue
+		}
+		// A line ending in an odd number of backslashes continues on the next
+		for strings.HasSuffix(line, `\`) && (len(line)-len(strings.TrimRight(line, `\`)))%2 == 1 && scanner.Scan() {
+			line = line[:len(line)-1] + strings.TrimLeft(scanner.Text(), " \t\f")
+		}
+
+		// The key ends at the first unescaped separator, and the value starts after it and any whitespace
+		end := len(line)
+		for i := 0; i < len(line); i++ {
+			if line[i] == '\\' {
+				i++
+			} else if strings.IndexByte("=: \t\f", line[i]) >= 0 {
+				end = i
+				break
+			}
+		}
+		key, value := line[:end], line[end:]
+		sep := value != "" && (value[0] == '=' || value[0] == ':')
+		if sep {
+			value = value[1:]
+		}
+		value = strings.TrimLeft(value, " \t\f")
+		// Whitespace between the key and a separator is allowed
+		if !sep && value != "" && (value[0] == '=' || value[0] == ':') {
+			value = strings.TrimLeft(value[1:], " \t\f")
+		}
+		props[unescapeProperty(key)] = unescapeProperty(value)
+	}
+	return props, scanner.Err()
+}
+
+// Replaces the escape sequences of a properties file
+func unescapeProperty(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 't':
+			b.WriteByte('\t')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 'f':
+			b.WriteByte('\f')
+		case 'u':
+			c, ok := hexEscape(s, i+1)
+			if !ok {
+				b.WriteByte('u')
+				break
+			}
+			i += 4
+			// Characters outside the Basic Multilingual Plane are escaped as UTF-16 surrogate pairs
+			if utf16.IsSurrogate(c) && strings.HasPrefix(s[i+1:], `\u`) {
+				if c2, ok := hexEscape(s, i+3); ok {
+					if r := utf16.DecodeRune(c, c2); r != unicode.ReplacementChar {
+						c = r
+						i += 6
+					}
+				}
+			}
+			b.WriteRune(c)
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+// Parses the four hex digits of a \u escape starting at s[i]
+func hexEscape(s string, i int) (rune, bool) {
+	if i+4 > len(s) {
+		return 0, false
+	}
+	c, err := strconv.ParseUint(s[i:i+4], 16, 16)
+	return rune(c), err == nil
+}
@@ -0,0 +1,143 @@
+package slimy
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Writes a gzipped level.dat holding a long at the given path of compounds, along with some other tags
+func writeLevelDat(t *testing.T, name string, seed int64, path ...string) {
+	var buf bytes.Buffer
+	tag := func(typ byte, name string) {
+		buf.WriteByte(typ)
+		binary.Write(&buf, binary.BigEndian, uint16(len(name)))
+		buf.WriteString(name)
+	}
+	tag(10, "")
+	for _, compound := range path[:len(path)-1] {
+		tag(10, compound)
+		tag(8, "LevelName")
+		binary.Write(&buf, binary.BigEndian, uint16(5))
+		buf.WriteString("world")
+		tag(3, "version")
+		binary.Write(&buf, binary.BigEndian, int32(19133))
+	}
+	tag(4, path[len(path)-1])
+	binary.Write(&buf, binary.BigEndian, seed)
+	buf.Write(make([]byte, len(path))) // End tags
+
+	var gz bytes.Buffer
+	w := gzip.NewWriter(&gz)
+	w.Write(buf.Bytes())
+	w.Close()
+	if err := os.MkdirAll(filepath.Dir(name), 0777); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(name, gz.Bytes(), 0666); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writeFile(t *testing.T, name, contents string) {
+	if err := os.MkdirAll(filepath.Dir(name), 0777); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(name, []byte(contents), 0666); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReadWorldSeed(t *testing.T) {
+	dir, err := ioutil.TempDir("", "slimy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	join := func(elem ...string) string { return filepath.Join(append([]string{dir}, elem...)...) }
+
+	// A world from 1.16 or later
+	writeLevelDat(t, join("new", "level.dat"), -4172144997902289642, "Data", "WorldGenSettings", "seed")
+	// A world from before 1.16
+	writeLevelDat(t, join("old", "level.dat"), 2151901553968352745, "Data", "RandomSeed")
+	// A world without a seed
+	writeLevelDat(t, join("none", "level.dat"), 5, "Data", "Time")
+
+	// A server whose world exists, with a level-seed that has since been changed
+	writeFile(t, join("server", "server.properties"), "#Minecraft server properties\nlevel-name=my\\ world\nlevel-seed=123\n")
+	writeLevelDat(t, join("server", "my world", "level.dat"), 42, "Data", "WorldGenSettings", "seed")
+	// A server whose world hasn't been created yet
+	writeFile(t, join("fresh", "server.properties"), "motd=A Minecraft Server\nlevel-seed = Glacier\\u00e9\\\n  s\n")
+	writeFile(t, join("numeric", "server.properties"), "level-seed:-00123\n")
+	writeFile(t, join("random", "server.properties"), "level-seed=\ngamemode=survival\n")
+
+	cases := []struct {
+		name     string
+		expected int64
+	}{
+		{join("new"), -4172144997902289642},
+		{join("new", "level.dat"), -4172144997902289642},
+		{join("old"), 2151901553968352745},
+		{join("server"), 42},
+		{join("server", "server.properties"), 42},
+		{join("fresh"), int64(JavaStringHash("Glacierés"))},
+		{join("numeric", "server.properties"), -123},
+	}
+	for _, c := range cases {
+		seed, err := ReadWorldSeed(c.name)
+		if err != nil {
+			t.Errorf("%s: %v", c.name, err)
+		} else if seed != c.expected {
+			t.Errorf("%s: expected %d, got %d", c.name, c.expected, seed)
+		}
+	}
+
+	for _, name := range []string{join("none"), join("random"), join("missing"), dir, join("fresh", "server.properties", "x")} {
+		if _, err := ReadWorldSeed(name); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestReadProperties(t *testing.T) {
+	props, err := readProperties(strings.NewReader(`# comment
+! another comment
+  key1 = value one  
+key2:value2
+key3 value3
+key\ 4=a\:b\=c
+key5 := x
+key6==y
+empty=
+continued=one\
+    two\\
+escapes=\t\u0041\uD83D\ude00\q
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{
+		"key1":      "value one  ",
+		"key2":      "value2",
+		"key3":      "value3",
+		"key 4":     "a:b=c",
+		"key5":      "= x",
+		"key6":      "=y",
+		"empty":     "",
+		"continued": "onetwo\\",
+		"escapes":   "\tA😀q",
+	}
+	for key, value := range expected {
+		if props[key] != value {
+			t.Errorf("%s: expected %q, got %q", key, value, props[key])
+		}
+	}
+	if len(props) != len(expected) {
+		t.Errorf("Expected %d properties, got %d: %v", len(expected), len(props), props)
+	}
+}